@@ -0,0 +1,42 @@
+// Package backend provides the external systems products are synced to
+package backend
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+)
+
+const (
+	// TypeMemory in memory backend
+	TypeMemory = "memory"
+	// TypeHTTP http json catalog backend
+	TypeHTTP = "http"
+)
+
+// ProductBackend external system products are mirrored to
+type ProductBackend interface {
+	// Upsert creates or updates the product in the backend
+	Upsert(ctx context.Context, pdt *pdtv1.Product) error
+	// Delete removes the product from the backend, deleting a missing product is not an error
+	Delete(ctx context.Context, pdt *pdtv1.Product) error
+}
+
+// New backend of the backend type, url and timeout are used by the http backend only
+func New(backendType, url string, timeout time.Duration) (ProductBackend, error) {
+	switch backendType {
+	case TypeMemory:
+		return NewMemoryBackend(), nil
+	case TypeHTTP:
+		if url == "" {
+			return nil, fmt.Errorf("url required for %s backend", TypeHTTP)
+		}
+
+		return NewHTTPBackend(url, &http.Client{Timeout: timeout}), nil
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", backendType)
+	}
+}
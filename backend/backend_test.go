@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+)
+
+func makeTestProduct() *pdtv1.Product {
+	return &pdtv1.Product{
+		ObjectMeta: metav1.ObjectMeta{Name: "testPdt", Namespace: "testNs"},
+		Spec:       pdtv1.ProductSpec{Brand: "testBrand", Price: 100, Categories: []string{"test"}},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		backendType string
+		url         string
+		wantErr     bool
+	}{
+		{name: "success memory backend", backendType: TypeMemory, wantErr: false},
+		{name: "success http backend", backendType: TypeHTTP, url: "http://catalog", wantErr: false},
+		{name: "failure http backend without url", backendType: TypeHTTP, wantErr: true},
+		{name: "failure unknown backend", backendType: "unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.backendType, tt.url, time.Second); (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMemoryBackend(t *testing.T) {
+	b := NewMemoryBackend()
+	pdt := makeTestProduct()
+
+	if err := b.Upsert(context.Background(), pdt); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	if got, ok := b.Get("testNs/testPdt"); !ok || got.Spec.Brand != pdt.Spec.Brand {
+		t.Errorf("Get() = %v, %v, want %v", got, ok, pdt)
+	}
+
+	if err := b.Delete(context.Background(), pdt); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if b.Len() != 0 {
+		t.Errorf("Len() = %v, want 0", b.Len())
+	}
+}
+
+func TestHTTPBackend(t *testing.T) {
+	stored := map[string]CatalogProduct{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/namespaces/testNs/products/broken":
+			http.Error(w, "catalog down", http.StatusInternalServerError)
+		case r.Method == http.MethodPut:
+			var cp CatalogProduct
+			if err := json.NewDecoder(r.Body).Decode(&cp); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			stored[r.URL.Path] = cp
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodDelete:
+			if _, ok := stored[r.URL.Path]; !ok {
+				http.NotFound(w, r)
+				return
+			}
+
+			delete(stored, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	b := NewHTTPBackend(srv.URL+"/", srv.Client())
+	pdt := makeTestProduct()
+	broken := makeTestProduct()
+	broken.Name = "broken"
+
+	tests := []struct {
+		name    string
+		call    func(context.Context, *pdtv1.Product) error
+		pdt     *pdtv1.Product
+		wantErr bool
+	}{
+		{name: "success upsert", call: b.Upsert, pdt: pdt, wantErr: false},
+		{name: "success delete", call: b.Delete, pdt: pdt, wantErr: false},
+		{name: "success delete not found", call: b.Delete, pdt: pdt, wantErr: false},
+		{name: "failure upsert server error", call: b.Upsert, pdt: broken, wantErr: true},
+		{name: "failure delete server error", call: b.Delete, pdt: broken, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(context.Background(), tt.pdt); (err != nil) != tt.wantErr {
+				t.Errorf("%s error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
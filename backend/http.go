@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+)
+
+// CatalogProduct product representation sent to the catalog service
+type CatalogProduct struct {
+	UID         string   `json:"uid"`
+	Namespace   string   `json:"namespace"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"displayName"`
+	Description string   `json:"description"`
+	Brand       string   `json:"brand,omitempty"`
+	Price       float64  `json:"price,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+}
+
+// HTTPBackend backend syncing products to the estore catalog service over http json
+//
+// products are stored with PUT and removed with DELETE on <url>/namespaces/<namespace>/products/<name>
+type HTTPBackend struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPBackend new http backend for the catalog service url
+func NewHTTPBackend(catalogURL string, client *http.Client) *HTTPBackend {
+	return &HTTPBackend{url: strings.TrimSuffix(catalogURL, "/"), client: client}
+}
+
+// Upsert creates or updates the product in the catalog
+func (b *HTTPBackend) Upsert(ctx context.Context, pdt *pdtv1.Product) error {
+	body, err := json.Marshal(CatalogProduct{
+		UID:         string(pdt.UID),
+		Namespace:   pdt.Namespace,
+		Name:        pdt.Name,
+		DisplayName: pdt.Spec.DisplayName,
+		Description: pdt.Spec.Description,
+		Brand:       pdt.Spec.Brand,
+		Price:       pdt.Spec.Price,
+		Categories:  pdt.Spec.Categories,
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.do(ctx, http.MethodPut, b.productURL(pdt), bytes.NewReader(body), http.StatusOK, http.StatusCreated, http.StatusNoContent)
+}
+
+// Delete removes the product from the catalog, a product missing in the catalog is considered deleted
+func (b *HTTPBackend) Delete(ctx context.Context, pdt *pdtv1.Product) error {
+	return b.do(ctx, http.MethodDelete, b.productURL(pdt), nil, http.StatusOK, http.StatusAccepted, http.StatusNoContent, http.StatusNotFound)
+}
+
+func (b *HTTPBackend) productURL(pdt *pdtv1.Product) string {
+	return fmt.Sprintf("%s/namespaces/%s/products/%s", b.url, url.PathEscape(pdt.Namespace), url.PathEscape(pdt.Name))
+}
+
+func (b *HTTPBackend) do(ctx context.Context, method, reqURL string, body io.Reader, expectedCodes ...int) error {
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("catalog %s %s failed: %w", method, reqURL, err)
+	}
+	defer resp.Body.Close()
+
+	for _, code := range expectedCodes {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	return &StatusError{Method: method, URL: reqURL, Code: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+}
+
+// StatusError catalog call answered with an unexpected status code
+type StatusError struct {
+	Method  string
+	URL     string
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("catalog %s %s failed with status %d: %s", e.Method, e.URL, e.Code, e.Message)
+}
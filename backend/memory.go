@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+)
+
+// MemoryBackend in memory backend, used for tests and as local stand-in for the catalog
+type MemoryBackend struct {
+	mu       sync.RWMutex
+	products map[string]*pdtv1.Product
+}
+
+// NewMemoryBackend new in memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{products: map[string]*pdtv1.Product{}}
+}
+
+// Upsert stores a copy of the product
+func (b *MemoryBackend) Upsert(ctx context.Context, pdt *pdtv1.Product) error {
+	key, err := cache.MetaNamespaceKeyFunc(pdt)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.products[key] = pdt.DeepCopy()
+
+	return nil
+}
+
+// Delete removes the product
+func (b *MemoryBackend) Delete(ctx context.Context, pdt *pdtv1.Product) error {
+	key, err := cache.MetaNamespaceKeyFunc(pdt)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.products, key)
+
+	return nil
+}
+
+// Get returns a copy of the product stored with the namespace/name key
+func (b *MemoryBackend) Get(key string) (*pdtv1.Product, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	pdt, ok := b.products[key]
+	if !ok {
+		return nil, false
+	}
+
+	return pdt.DeepCopy(), true
+}
+
+// Len number of products stored
+func (b *MemoryBackend) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.products)
+}
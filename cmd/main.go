@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"sync/atomic"
 
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 	corev1 "k8s.io/api/core/v1"
 	kubeclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
@@ -15,11 +20,17 @@ import (
 	gc "github.com/arutselvan15/estore-common/config"
 	"github.com/arutselvan15/estore-common/signals"
 	"github.com/arutselvan15/estore-product-kube-client/pkg/client/clientset/versioned/scheme"
-	pdtInformers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions"
+	pdtv1Informers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions/estore/v1"
+	gLog "github.com/arutselvan15/go-utils/log"
 
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
 	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
 	"github.com/arutselvan15/estore-product-kube-controller/controllers"
+	"github.com/arutselvan15/estore-product-kube-controller/health"
 	cLog "github.com/arutselvan15/estore-product-kube-controller/log"
+	"github.com/arutselvan15/estore-product-kube-controller/metrics"
+	"github.com/arutselvan15/estore-product-kube-controller/validation"
+	"github.com/arutselvan15/estore-product-kube-controller/webhook"
 )
 
 func main() {
@@ -32,6 +43,38 @@ func main() {
 		stopCh = signals.SetupSignalHandler()
 	)
 
+	// controller configuration from config.yaml, env and flags
+	conf, err := cfg.Load(viper.GetViper(), os.Args[1:])
+	if err == pflag.ErrHelp {
+		os.Exit(0)
+	}
+
+	if err != nil {
+		log.Errorf("error loading configuration: %v", err)
+		os.Exit(cfg.ExitErrorCode)
+	}
+
+	conf.Apply()
+
+	// product specs are validated against the built-in rules before they are reconciled
+	controllers.SpecValidator = validation.NewValidator(validation.DefaultRules()...)
+
+	// log level, freeze window and system and blacklist lists, replaced on config file changes
+	rt, err := cfg.RuntimeFromViper(viper.GetViper())
+	if err != nil {
+		log.Errorf("error loading configuration: %v", err)
+		os.Exit(cfg.ExitErrorCode)
+	}
+
+	cfg.SetRuntime(rt)
+	log.SetLevel(gLog.LevelLog(rt.LogLevel))
+
+	// the admission webhooks need no kube clients, they only decide on the products they are sent
+	if conf.Mode == cfg.ModeWebhook {
+		runWebhook(conf, rt, stopCh)
+		return
+	}
+
 	// kube config defined in env
 	if gc.GetKubeConfigPath() != "" {
 		config, err = clientcmd.BuildConfigFromFlags("", gc.GetKubeConfigPath())
@@ -56,13 +99,24 @@ func main() {
 	}
 
 	// product object related
-	// retrieve our custom resource informer which was generated from the code generator and pass it the custom
-	// resource client, specifying we should be looking through all namespaces for listing and watching
-	pdtInformerFactory := pdtInformers.NewSharedInformerFactory(estoreClients.GetProductClient(), cfg.ResyncDuration)
-	pdtInformer := pdtInformerFactory.Estore().V1().Products()
+	// retrieve our custom resource informers which were generated from the code generator and pass them the custom
+	// resource client, one informer factory per watched namespace listing and watching the products in scope only
+	scope, err := controllers.NewScope(conf.Watch.Namespaces, conf.Watch.LabelSelector, conf.Watch.FieldSelector)
+	if err != nil {
+		log.Errorf("error creating watch scope: %v", err)
+		os.Exit(cfg.ExitErrorCode)
+	}
+
+	pdtInformerFactories := scope.NewInformerFactories(estoreClients.GetProductClient(), conf.ResyncPeriod)
+
+	pdtInformers := make([]pdtv1Informers.ProductInformer, 0, len(pdtInformerFactories))
+	for _, pdtInformerFactory := range pdtInformerFactories {
+		pdtInformers = append(pdtInformers, pdtInformerFactory.Estore().V1().Products())
+	}
 
 	// creating the rate limited work queue required for the controller
-	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), cfg.WorkQueueName)
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(controllers.NewRateLimiter(conf.RateLimiter.BaseDelay,
+		conf.RateLimiter.MaxDelay, conf.RateLimiter.QPS, conf.RateLimiter.Burst), conf.QueueName)
 
 	// event broad caster
 	eventBroadcaster := record.NewBroadcaster()
@@ -73,11 +127,157 @@ func main() {
 
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: fmt.Sprintf("%s-%s", cfg.ResourceName, cfg.Component)})
 
-	pdtController := controllers.NewController(pdtInformer, pdtQueue, estoreClients, recorder, controllers.ProcessItem)
+	// external system products are mirrored to
+	pdtBackend, err := backend.New(conf.Backend.Type, conf.Backend.URL, conf.Backend.Timeout)
+	if err != nil {
+		log.Errorf("error creating product backend: %v", err)
+		os.Exit(cfg.ExitErrorCode)
+	}
+
+	pdtController := controllers.NewController(pdtInformers, scope, pdtQueue, estoreClients, pdtBackend, recorder, controllers.ProcessItem)
+
+	// metrics of reconciles, work queue and products by phase
+	if err = metrics.RegisterProductPhases(pdtController.Lister()); err != nil {
+		log.Errorf("error registering product metrics: %v", err)
+		os.Exit(cfg.ExitErrorCode)
+	}
+
+	// the quarantined products are listed next to the metrics
+	metricsServer := metrics.NewServer(conf.MetricsAddress, metrics.Endpoint{Path: controllers.QuarantinePath, Handler: pdtController.QuarantineHandler()})
+
+	go func() {
+		if serveErr := metricsServer.ListenAndServe(); serveErr != nil && serveErr != http.ErrServerClosed {
+			log.Errorf("error serving metrics on %s: %v", conf.MetricsAddress, serveErr)
+			os.Exit(cfg.ExitErrorCode)
+		}
+	}()
+
+	defer metricsServer.Close()
+
+	// liveness fails on stalled workers, readiness passes once this replica runs the workers on a synced cache
+	probeServer := health.NewServer(conf.ProbeAddress, func() error {
+		return pdtController.Healthy(conf.LivenessWindow)
+	}, pdtController.Ready)
+
+	go func() {
+		if serveErr := probeServer.ListenAndServe(); serveErr != nil && serveErr != http.ErrServerClosed {
+			log.Errorf("error serving probes on %s: %v", conf.ProbeAddress, serveErr)
+			os.Exit(cfg.ExitErrorCode)
+		}
+	}()
+
+	defer probeServer.Close()
+
+	// apply config file changes live, the worker count of a replica not leading applies once it leads
+	workerCount := int32(conf.Workers)
+
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		configWatcher := cfg.NewWatcher(configFile, os.Args[1:], conf, rt, func(newConf *cfg.Config, newRt *cfg.Runtime, changes []string) {
+			for _, change := range changes {
+				log.Infof("config file %s reloaded, %s", configFile, change)
+			}
+
+			log.SetLevel(gLog.LevelLog(newRt.LogLevel))
+			cfg.SetRuntime(newRt)
+			atomic.StoreInt32(&workerCount, int32(newConf.Workers))
+			pdtController.SetWorkers(newConf.Workers)
+			pdtController.RequeueNotReady()
+		}, func(reloadErr error) {
+			log.Errorf("config file %s reload rejected, keeping last good config: %v", configFile, reloadErr)
+		})
+
+		go func() {
+			if watchErr := configWatcher.Run(stopCh); watchErr != nil {
+				log.Errorf("error watching config file %s: %v", configFile, watchErr)
+			}
+		}()
+	}
 
 	// notice that there is no need to run Start methods in a separate goroutine. (i.e. go kubeInformerFactory.Start(stopCh)
 	// Start method is non-blocking and runs all registered informers in a dedicated goroutine.
-	pdtInformerFactory.Start(stopCh)
+	for _, pdtInformerFactory := range pdtInformerFactories {
+		pdtInformerFactory.Start(stopCh)
+	}
+
+	// the controller drains its in-flight reconciles on the first shutdown signal, leader election gives up the lease
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+
+	run := func(runCtx context.Context) {
+		pdtController.Run(runCtx, int(atomic.LoadInt32(&workerCount)))
+	}
 
-	pdtController.Run(cfg.WorkerCount, stopCh)
+	if !conf.LeaderElection.Enabled {
+		run(ctx)
+		return
+	}
+
+	identity, err := os.Hostname()
+	if err != nil {
+		log.Errorf("error getting identity for leader election: %v", err)
+		os.Exit(cfg.ExitErrorCode)
+	}
+
+	// only the replica holding the lease runs the workers, a replica losing the lease exits to rejoin the election
+	if err = controllers.RunWithLeaderElection(ctx, estoreClients, controllers.NewLeaderElectionConfig(identity), run); err != nil {
+		log.Errorf("error running leader election: %v", err)
+		os.Exit(cfg.ExitErrorCode)
+	}
+}
+
+// runWebhook serves the admission webhooks until stopCh is closed, config file changes of the runtime settings
+// apply live
+func runWebhook(conf *cfg.Config, rt *cfg.Runtime, stopCh <-chan struct{}) {
+	log := cLog.GetLogger()
+
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		configWatcher := cfg.NewWatcher(configFile, os.Args[1:], conf, rt, func(newConf *cfg.Config, newRt *cfg.Runtime, changes []string) {
+			for _, change := range changes {
+				log.Infof("config file %s reloaded, %s", configFile, change)
+			}
+
+			log.SetLevel(gLog.LevelLog(newRt.LogLevel))
+			cfg.SetRuntime(newRt)
+		}, func(reloadErr error) {
+			log.Errorf("config file %s reload rejected, keeping last good config: %v", configFile, reloadErr)
+		})
+
+		go func() {
+			if watchErr := configWatcher.Run(stopCh); watchErr != nil {
+				log.Errorf("error watching config file %s: %v", configFile, watchErr)
+			}
+		}()
+	}
+
+	// the webhooks share the watch scope of the controllers, only the products they reconcile get the finalizer
+	scope, err := controllers.NewScope(conf.Watch.Namespaces, conf.Watch.LabelSelector, conf.Watch.FieldSelector)
+	if err != nil {
+		log.Errorf("error creating watch scope: %v", err)
+		os.Exit(cfg.ExitErrorCode)
+	}
+
+	webhookServer := webhook.NewServer(conf.Webhook.Address, controllers.SpecValidator, scope)
+
+	go func() {
+		<-stopCh
+
+		ctx, cancel := context.WithTimeout(context.Background(), conf.ShutdownGracePeriod)
+		defer cancel()
+
+		if shutdownErr := webhookServer.Shutdown(ctx); shutdownErr != nil {
+			log.Errorf("error shutting down webhooks: %v", shutdownErr)
+		}
+	}()
+
+	log.Infof("serving admission webhooks on %s", conf.Webhook.Address)
+
+	if serveErr := webhookServer.ListenAndServeTLS(conf.Webhook.CertFile, conf.Webhook.KeyFile); serveErr != nil && serveErr != http.ErrServerClosed {
+		log.Errorf("error serving webhooks on %s: %v", conf.Webhook.Address, serveErr)
+		os.Exit(cfg.ExitErrorCode)
+	}
 }
@@ -8,19 +8,102 @@ const (
 	ResourceName = "product"
 	// Component component name
 	Component = "controller"
-	// WorkQueueName work queue name
-	WorkQueueName = "product"
 	// ExitErrorCode exit code
 	ExitErrorCode = 1
 	// ProcessItem process item
 	ProcessItem = "processItem"
-	// ProductOperatorFinalizer finalizers
-	ProductOperatorFinalizer = "operator.finalizers.product.estore.com"
+	// ObservedGenerationAnnotation annotation recording the last generation reconciled successfully
+	ObservedGenerationAnnotation = "product.estore.com/observed-generation"
+	// ReleaseQuarantineAnnotation annotation releasing a quarantined product when set to a new value
+	ReleaseQuarantineAnnotation = "product.estore.com/release-quarantine"
+	// ModeController mode running the controller
+	ModeController = "controller"
+	// ModeWebhook mode serving the admission webhooks
+	ModeWebhook = "webhook"
+	// FieldName product field the watch field selector may select on
+	FieldName = "metadata.name"
+	// FieldNamespace product field the watch field selector may select on
+	FieldNamespace = "metadata.namespace"
 )
 
+// defaults, replaced by Apply with the loaded configuration
 var (
+	// Mode what the binary runs, ModeController or ModeWebhook
+	Mode = ModeController
+	// WorkQueueName work queue name
+	WorkQueueName = "product"
+	// ProductOperatorFinalizer finalizers
+	ProductOperatorFinalizer = "operator.finalizers.product.estore.com"
 	// ResyncDuration resync duration in minutes
 	ResyncDuration = 15 * time.Minute
 	// WorkerCount worker count
 	WorkerCount = 1
+	// LeaderElectionEnabled run the controller only on the replica holding the lease
+	LeaderElectionEnabled = true
+	// LeaseName name of the lease object used for leader election
+	LeaseName = "product-controller"
+	// LeaseNamespace namespace of the lease object used for leader election
+	LeaseNamespace = "default"
+	// LeaseDuration duration non-leader candidates wait before forcing to acquire the lease
+	LeaseDuration = 15 * time.Second
+	// RenewDeadline duration the leader retries refreshing the lease before giving up
+	RenewDeadline = 10 * time.Second
+	// RetryPeriod duration candidates wait between leader election actions
+	RetryPeriod = 2 * time.Second
+	// MetricsAddress address the metrics endpoint listens on
+	MetricsAddress = ":8080"
+	// ProbeAddress address the liveness and readiness probes listen on
+	ProbeAddress = ":8081"
+	// LivenessWindow duration workers may not pull from a non empty queue before liveness fails
+	LivenessWindow = 5 * time.Minute
+	// BackendType backend products are synced to, memory or http
+	BackendType = "memory"
+	// BackendURL catalog service url used by the http backend
+	BackendURL = ""
+	// BackendTimeout timeout of a single call to the http backend
+	BackendTimeout = 30 * time.Second
+	// LiveGetFallback fetch products missing in the cache from the api server before treating them as gone
+	LiveGetFallback = true
+	// WatchNamespaces namespaces watched for products, all namespaces when empty
+	WatchNamespaces []string
+	// LabelSelector label selector products must match to be watched
+	LabelSelector = ""
+	// FieldSelector field selector products must match to be watched
+	FieldSelector = ""
+	// FreezeAllowDeletes let deletions of products with the finalizer through during the freeze window
+	FreezeAllowDeletes = true
+	// FreezeRecheckInterval longest duration a key deferred by the freeze waits before it is checked again
+	FreezeRecheckInterval = 5 * time.Minute
+	// RateLimiterBaseDelay first retry delay of a failing key, doubled on every failure
+	RateLimiterBaseDelay = 5 * time.Millisecond
+	// RateLimiterMaxDelay longest retry delay of a failing key
+	RateLimiterMaxDelay = 1000 * time.Second
+	// RateLimiterQPS overall rate keys are added back to the queue
+	RateLimiterQPS = 10.0
+	// RateLimiterBurst burst of keys added back to the queue
+	RateLimiterBurst = 100
+	// MaxRetries failures of a key before it is dropped and its product marked failed, 0 retries forever
+	MaxRetries = 15
+	// MaxPanics panicking reconciles of a key in a row before it is quarantined and no longer retried
+	MaxPanics = 3
+	// ReconcileTimeout longest duration a single reconcile may take before its backend and api calls are cancelled
+	ReconcileTimeout = 2 * time.Minute
+	// ValidationPriceMin lowest valid product price
+	ValidationPriceMin = 0.0
+	// ValidationPriceMax highest valid product price
+	ValidationPriceMax = 1000000.0
+	// ValidationCurrencyDecimals decimal places a product price may have
+	ValidationCurrencyDecimals = 2
+	// ValidationCategories categories products may be listed in, any category when empty
+	ValidationCategories []string
+	// ValidationDisplayNamePattern pattern a product display name must match when set
+	ValidationDisplayNamePattern = `^[\p{L}\p{N}][\p{L}\p{N} .,&'()/+_-]{0,99}$`
+	// WebhookAddress address the admission webhooks listen on
+	WebhookAddress = ":8443"
+	// WebhookCertFile tls certificate of the admission webhooks
+	WebhookCertFile = "/etc/webhook/certs/tls.crt"
+	// WebhookKeyFile tls key of the admission webhooks
+	WebhookKeyFile = "/etc/webhook/certs/tls.key"
+	// ShutdownGracePeriod longest duration in-flight reconciles are waited for on shutdown before they are abandoned
+	ShutdownGracePeriod = 30 * time.Second
 )
@@ -0,0 +1,409 @@
+// Package config config
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Config controller configuration, read from the controller section of config.yaml, environment variables and
+// command line flags, in increasing order of precedence
+type Config struct {
+	Mode                string
+	Workers             int
+	ResyncPeriod        time.Duration
+	QueueName           string
+	Finalizer           string
+	MetricsAddress      string
+	ProbeAddress        string
+	LivenessWindow      time.Duration
+	LiveGetFallback     bool
+	RateLimiter         RateLimiterConfig
+	MaxRetries          int
+	MaxPanics           int
+	ReconcileTimeout    time.Duration
+	ShutdownGracePeriod time.Duration
+	LeaderElection      LeaderElectionConfig
+	Backend             BackendConfig
+	Watch               WatchConfig
+	Freeze              FreezeConfig
+	Validation          ValidationConfig
+	Webhook             WebhookConfig
+}
+
+// RateLimiterConfig retry delays of failing keys and overall rate keys are added back to the queue
+type RateLimiterConfig struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	QPS       float64
+	Burst     int
+}
+
+// LeaderElectionConfig lease used to elect the replica running the workers
+type LeaderElectionConfig struct {
+	Enabled        bool
+	LeaseName      string
+	LeaseNamespace string
+	LeaseDuration  time.Duration
+	RenewDeadline  time.Duration
+	RetryPeriod    time.Duration
+}
+
+// BackendConfig backend products are synced to
+type BackendConfig struct {
+	Type    string
+	URL     string
+	Timeout time.Duration
+}
+
+// WatchConfig products watched, all products when empty
+type WatchConfig struct {
+	Namespaces    []string
+	LabelSelector string
+	FieldSelector string
+}
+
+// FreezeConfig controller behaviour inside the app.freeze window
+type FreezeConfig struct {
+	AllowDeletes    bool
+	RecheckInterval time.Duration
+}
+
+// ValidationConfig rules the product spec is validated against
+type ValidationConfig struct {
+	PriceMin           float64
+	PriceMax           float64
+	CurrencyDecimals   int
+	Categories         []string
+	DisplayNamePattern string
+}
+
+// WebhookConfig tls server of the admission webhooks
+type WebhookConfig struct {
+	Address  string
+	CertFile string
+	KeyFile  string
+}
+
+// option a configuration value with its config key, command line flag and environment variable
+type option struct {
+	key   string
+	flag  string
+	env   string
+	value interface{}
+	usage string
+}
+
+// ConfigFileFlag flag of the config file path, the file is looked up in /etc/viper and ./ otherwise
+const ConfigFileFlag = "config"
+
+func options() []option {
+	return []option{
+		{"controller.mode", "mode", "CONTROLLER_MODE", Mode, "run the controller or serve the admission webhooks, controller or webhook"},
+		{"controller.workers", "workers", "CONTROLLER_WORKERS", WorkerCount, "number of workers processing products"},
+		{"controller.resyncPeriod", "resync-period", "CONTROLLER_RESYNC_PERIOD", ResyncDuration, "informer resync period"},
+		{"controller.queueName", "queue-name", "CONTROLLER_QUEUE_NAME", WorkQueueName, "work queue name"},
+		{"controller.finalizer", "finalizer", "CONTROLLER_FINALIZER", ProductOperatorFinalizer, "finalizer added to products"},
+		{"controller.metricsAddress", "metrics-address", "CONTROLLER_METRICS_ADDRESS", MetricsAddress, "address the metrics endpoint listens on"},
+		{"controller.probeAddress", "probe-address", "CONTROLLER_PROBE_ADDRESS", ProbeAddress, "address the liveness and readiness probes listen on"},
+		{"controller.livenessWindow", "liveness-window", "CONTROLLER_LIVENESS_WINDOW", LivenessWindow, "duration workers may not pull from a non empty queue before liveness fails"},
+		{"controller.liveGetFallback", "live-get-fallback", "CONTROLLER_LIVE_GET_FALLBACK", LiveGetFallback, "fetch products missing in the cache from the api server"},
+		{"controller.rateLimiter.baseDelay", "rate-limiter-base-delay", "CONTROLLER_RATE_LIMITER_BASE_DELAY", RateLimiterBaseDelay, "first retry delay of a failing key"},
+		{"controller.rateLimiter.maxDelay", "rate-limiter-max-delay", "CONTROLLER_RATE_LIMITER_MAX_DELAY", RateLimiterMaxDelay, "longest retry delay of a failing key"},
+		{"controller.rateLimiter.qps", "rate-limiter-qps", "CONTROLLER_RATE_LIMITER_QPS", RateLimiterQPS, "overall rate keys are added back to the queue"},
+		{"controller.rateLimiter.burst", "rate-limiter-burst", "CONTROLLER_RATE_LIMITER_BURST", RateLimiterBurst, "burst of keys added back to the queue"},
+		{"controller.maxRetries", "max-retries", "CONTROLLER_MAX_RETRIES", MaxRetries, "failures of a key before it is dropped, 0 retries forever"},
+		{"controller.maxPanics", "max-panics", "CONTROLLER_MAX_PANICS", MaxPanics, "panics of a key in a row before it is quarantined"},
+		{"controller.reconcileTimeout", "reconcile-timeout", "CONTROLLER_RECONCILE_TIMEOUT", ReconcileTimeout, "longest duration of a single reconcile"},
+		{"controller.shutdownGracePeriod", "shutdown-grace-period", "CONTROLLER_SHUTDOWN_GRACE_PERIOD", ShutdownGracePeriod, "longest wait for in-flight reconciles on shutdown"},
+		{"controller.leaderElection.enabled", "leader-elect", "CONTROLLER_LEADER_ELECT", LeaderElectionEnabled, "run the workers only on the replica holding the lease"},
+		{"controller.leaderElection.leaseName", "lease-name", "CONTROLLER_LEASE_NAME", LeaseName, "name of the leader election lease"},
+		{"controller.leaderElection.leaseNamespace", "lease-namespace", "CONTROLLER_LEASE_NAMESPACE", LeaseNamespace, "namespace of the leader election lease"},
+		{"controller.leaderElection.leaseDuration", "lease-duration", "CONTROLLER_LEASE_DURATION", LeaseDuration, "duration candidates wait before forcing to acquire the lease"},
+		{"controller.leaderElection.renewDeadline", "renew-deadline", "CONTROLLER_RENEW_DEADLINE", RenewDeadline, "duration the leader retries refreshing the lease"},
+		{"controller.leaderElection.retryPeriod", "retry-period", "CONTROLLER_RETRY_PERIOD", RetryPeriod, "duration candidates wait between leader election actions"},
+		{"controller.backend.type", "backend-type", "CONTROLLER_BACKEND_TYPE", BackendType, "backend products are synced to, memory or http"},
+		{"controller.backend.url", "backend-url", "CONTROLLER_BACKEND_URL", BackendURL, "catalog service url used by the http backend"},
+		{"controller.backend.timeout", "backend-timeout", "CONTROLLER_BACKEND_TIMEOUT", BackendTimeout, "timeout of a single call to the http backend"},
+		{"controller.watch.namespaces", "watch-namespaces", "CONTROLLER_WATCH_NAMESPACES", strings.Join(WatchNamespaces, ","), "comma separated namespaces watched, all namespaces when empty"},
+		{"controller.watch.labelSelector", "label-selector", "CONTROLLER_LABEL_SELECTOR", LabelSelector, "label selector products must match"},
+		{"controller.watch.fieldSelector", "field-selector", "CONTROLLER_FIELD_SELECTOR", FieldSelector, "field selector on metadata.name and metadata.namespace products must match"},
+		{"controller.freeze.allowDeletes", "freeze-allow-deletes", "CONTROLLER_FREEZE_ALLOW_DELETES", FreezeAllowDeletes, "process deletions of products during the freeze window"},
+		{"controller.freeze.recheckInterval", "freeze-recheck-interval", "CONTROLLER_FREEZE_RECHECK_INTERVAL", FreezeRecheckInterval, "longest wait of a key deferred by the freeze"},
+		{"controller.webhook.address", "webhook-address", "CONTROLLER_WEBHOOK_ADDRESS", WebhookAddress, "address the admission webhooks listen on"},
+		{"controller.webhook.certFile", "webhook-cert-file", "CONTROLLER_WEBHOOK_CERT_FILE", WebhookCertFile, "tls certificate of the admission webhooks"},
+		{"controller.webhook.keyFile", "webhook-key-file", "CONTROLLER_WEBHOOK_KEY_FILE", WebhookKeyFile, "tls key of the admission webhooks"},
+		{"controller.validation.priceMin", "validation-price-min", "CONTROLLER_VALIDATION_PRICE_MIN", ValidationPriceMin, "lowest valid product price"},
+		{"controller.validation.priceMax", "validation-price-max", "CONTROLLER_VALIDATION_PRICE_MAX", ValidationPriceMax, "highest valid product price"},
+		{"controller.validation.currencyDecimals", "validation-currency-decimals", "CONTROLLER_VALIDATION_CURRENCY_DECIMALS", ValidationCurrencyDecimals, "decimal places a product price may have"},
+		{"controller.validation.categories", "validation-categories", "CONTROLLER_VALIDATION_CATEGORIES", strings.Join(ValidationCategories, ","), "comma separated categories products may be listed in, any category when empty"},
+		{"controller.validation.displayNamePattern", "validation-display-name-pattern", "CONTROLLER_VALIDATION_DISPLAY_NAME_PATTERN", ValidationDisplayNamePattern, "pattern a product display name must match when set"},
+	}
+}
+
+// Load loads the configuration into v from the config file, environment and args, then validates it
+func Load(v *viper.Viper, args []string) (*Config, error) {
+	flags := pflag.NewFlagSet(ResourceName+"-"+Component, pflag.ContinueOnError)
+	configFile := flags.String(ConfigFileFlag, "", "path of the config file")
+
+	for _, o := range options() {
+		switch value := o.value.(type) {
+		case int:
+			flags.Int(o.flag, value, o.usage)
+		case float64:
+			flags.Float64(o.flag, value, o.usage)
+		case bool:
+			flags.Bool(o.flag, value, o.usage)
+		case time.Duration:
+			flags.Duration(o.flag, value, o.usage)
+		default:
+			flags.String(o.flag, fmt.Sprint(value), o.usage)
+		}
+
+		v.SetDefault(o.key, o.value)
+
+		if err := v.BindEnv(o.key, o.env); err != nil {
+			return nil, err
+		}
+
+		if err := v.BindPFlag(o.key, flags.Lookup(o.flag)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		v.SetConfigFile(*configFile)
+
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("reading config file %s failed: %v", *configFile, err)
+		}
+	}
+
+	c := FromViper(v)
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// FromViper configuration currently held by v, not validated
+func FromViper(v *viper.Viper) *Config {
+	return &Config{
+		Mode:            v.GetString("controller.mode"),
+		Workers:         v.GetInt("controller.workers"),
+		ResyncPeriod:    v.GetDuration("controller.resyncPeriod"),
+		QueueName:       v.GetString("controller.queueName"),
+		Finalizer:       v.GetString("controller.finalizer"),
+		MetricsAddress:  v.GetString("controller.metricsAddress"),
+		ProbeAddress:    v.GetString("controller.probeAddress"),
+		LivenessWindow:  v.GetDuration("controller.livenessWindow"),
+		LiveGetFallback: v.GetBool("controller.liveGetFallback"),
+		RateLimiter: RateLimiterConfig{
+			BaseDelay: v.GetDuration("controller.rateLimiter.baseDelay"),
+			MaxDelay:  v.GetDuration("controller.rateLimiter.maxDelay"),
+			QPS:       v.GetFloat64("controller.rateLimiter.qps"),
+			Burst:     v.GetInt("controller.rateLimiter.burst"),
+		},
+		MaxRetries:          v.GetInt("controller.maxRetries"),
+		MaxPanics:           v.GetInt("controller.maxPanics"),
+		ReconcileTimeout:    v.GetDuration("controller.reconcileTimeout"),
+		ShutdownGracePeriod: v.GetDuration("controller.shutdownGracePeriod"),
+		LeaderElection: LeaderElectionConfig{
+			Enabled:        v.GetBool("controller.leaderElection.enabled"),
+			LeaseName:      v.GetString("controller.leaderElection.leaseName"),
+			LeaseNamespace: v.GetString("controller.leaderElection.leaseNamespace"),
+			LeaseDuration:  v.GetDuration("controller.leaderElection.leaseDuration"),
+			RenewDeadline:  v.GetDuration("controller.leaderElection.renewDeadline"),
+			RetryPeriod:    v.GetDuration("controller.leaderElection.retryPeriod"),
+		},
+		Backend: BackendConfig{
+			Type:    v.GetString("controller.backend.type"),
+			URL:     v.GetString("controller.backend.url"),
+			Timeout: v.GetDuration("controller.backend.timeout"),
+		},
+		Watch: WatchConfig{
+			Namespaces:    splitList(v.GetString("controller.watch.namespaces")),
+			LabelSelector: v.GetString("controller.watch.labelSelector"),
+			FieldSelector: v.GetString("controller.watch.fieldSelector"),
+		},
+		Freeze: FreezeConfig{
+			AllowDeletes:    v.GetBool("controller.freeze.allowDeletes"),
+			RecheckInterval: v.GetDuration("controller.freeze.recheckInterval"),
+		},
+		Webhook: WebhookConfig{
+			Address:  v.GetString("controller.webhook.address"),
+			CertFile: v.GetString("controller.webhook.certFile"),
+			KeyFile:  v.GetString("controller.webhook.keyFile"),
+		},
+		Validation: ValidationConfig{
+			PriceMin:           v.GetFloat64("controller.validation.priceMin"),
+			PriceMax:           v.GetFloat64("controller.validation.priceMax"),
+			CurrencyDecimals:   v.GetInt("controller.validation.currencyDecimals"),
+			Categories:         splitList(v.GetString("controller.validation.categories")),
+			DisplayNamePattern: v.GetString("controller.validation.displayNamePattern"),
+		},
+	}
+}
+
+// Validate returns every invalid value in a single error
+func (c *Config) Validate() error {
+	var errs []string
+
+	check := func(valid bool, format string, args ...interface{}) {
+		if !valid {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Mode == ModeController || c.Mode == ModeWebhook, "mode %q must be %s or %s", c.Mode, ModeController, ModeWebhook)
+	check(c.Workers >= 1, "workers %d must be at least 1", c.Workers)
+	check(c.ResyncPeriod >= 0, "resync period %s must not be negative", c.ResyncPeriod)
+	check(c.QueueName != "", "queue name must not be empty")
+
+	for _, msg := range validation.IsQualifiedName(c.Finalizer) {
+		errs = append(errs, fmt.Sprintf("finalizer %q: %s", c.Finalizer, msg))
+	}
+
+	for name, addr := range map[string]string{"metrics address": c.MetricsAddress, "probe address": c.ProbeAddress} {
+		_, _, err := net.SplitHostPort(addr)
+		check(err == nil, "%s %q: %v", name, addr, err)
+	}
+
+	check(c.LivenessWindow > 0, "liveness window %s must be positive", c.LivenessWindow)
+
+	check(c.RateLimiter.BaseDelay > 0, "rate limiter base delay %s must be positive", c.RateLimiter.BaseDelay)
+	check(c.RateLimiter.MaxDelay >= c.RateLimiter.BaseDelay, "rate limiter max delay %s must not be less than base delay %s",
+		c.RateLimiter.MaxDelay, c.RateLimiter.BaseDelay)
+	check(c.RateLimiter.QPS > 0, "rate limiter qps %v must be positive", c.RateLimiter.QPS)
+	check(c.RateLimiter.Burst >= 1, "rate limiter burst %d must be at least 1", c.RateLimiter.Burst)
+	check(c.MaxRetries >= 0, "max retries %d must not be negative", c.MaxRetries)
+	check(c.MaxPanics >= 1, "max panics %d must be at least 1", c.MaxPanics)
+	check(c.ReconcileTimeout > 0, "reconcile timeout %s must be positive", c.ReconcileTimeout)
+	check(c.ShutdownGracePeriod >= 0, "shutdown grace period %s must not be negative", c.ShutdownGracePeriod)
+
+	if le := c.LeaderElection; le.Enabled {
+		check(le.LeaseName != "" && le.LeaseNamespace != "", "lease name and namespace must not be empty")
+		check(le.RetryPeriod > 0, "retry period %s must be positive", le.RetryPeriod)
+		check(le.RenewDeadline > le.RetryPeriod, "renew deadline %s must be greater than retry period %s", le.RenewDeadline, le.RetryPeriod)
+		check(le.LeaseDuration > le.RenewDeadline, "lease duration %s must be greater than renew deadline %s", le.LeaseDuration, le.RenewDeadline)
+	}
+
+	switch c.Backend.Type {
+	case "memory":
+	case "http":
+		u, err := url.Parse(c.Backend.URL)
+		check(err == nil && u.Scheme != "" && u.Host != "", "backend url %q must be an absolute url", c.Backend.URL)
+	default:
+		errs = append(errs, fmt.Sprintf("backend type %q must be memory or http", c.Backend.Type))
+	}
+
+	check(c.Backend.Timeout > 0, "backend timeout %s must be positive", c.Backend.Timeout)
+
+	_, err := labels.Parse(c.Watch.LabelSelector)
+	check(err == nil, "label selector %q: %v", c.Watch.LabelSelector, err)
+
+	fieldSelector, err := fields.ParseSelector(c.Watch.FieldSelector)
+	check(err == nil, "field selector %q: %v", c.Watch.FieldSelector, err)
+
+	if err == nil {
+		// products outside the cache are matched against these fields only
+		for _, requirement := range fieldSelector.Requirements() {
+			check(requirement.Field == FieldName || requirement.Field == FieldNamespace,
+				"field selector field %q is not supported, only %s and %s are", requirement.Field, FieldName, FieldNamespace)
+		}
+	}
+
+	check(c.Freeze.RecheckInterval > 0, "freeze recheck interval %s must be positive", c.Freeze.RecheckInterval)
+
+	if c.Mode == ModeWebhook {
+		_, _, err = net.SplitHostPort(c.Webhook.Address)
+		check(err == nil, "webhook address %q: %v", c.Webhook.Address, err)
+		check(c.Webhook.CertFile != "" && c.Webhook.KeyFile != "", "webhook cert and key file must not be empty")
+	}
+
+	check(c.Validation.PriceMax >= c.Validation.PriceMin, "validation price max %v must not be less than price min %v",
+		c.Validation.PriceMax, c.Validation.PriceMin)
+	check(c.Validation.CurrencyDecimals >= 0, "validation currency decimals %d must not be negative", c.Validation.CurrencyDecimals)
+
+	_, err = regexp.Compile(c.Validation.DisplayNamePattern)
+	check(err == nil, "validation display name pattern %q: %v", c.Validation.DisplayNamePattern, err)
+
+	if len(errs) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// Apply sets the package level settings read by the controller to the configuration
+func (c *Config) Apply() {
+	Mode = c.Mode
+	WorkerCount = c.Workers
+	ResyncDuration = c.ResyncPeriod
+	WorkQueueName = c.QueueName
+	ProductOperatorFinalizer = c.Finalizer
+	MetricsAddress = c.MetricsAddress
+	ProbeAddress = c.ProbeAddress
+	LivenessWindow = c.LivenessWindow
+	LiveGetFallback = c.LiveGetFallback
+	RateLimiterBaseDelay = c.RateLimiter.BaseDelay
+	RateLimiterMaxDelay = c.RateLimiter.MaxDelay
+	RateLimiterQPS = c.RateLimiter.QPS
+	RateLimiterBurst = c.RateLimiter.Burst
+	MaxRetries = c.MaxRetries
+	MaxPanics = c.MaxPanics
+	ReconcileTimeout = c.ReconcileTimeout
+	ShutdownGracePeriod = c.ShutdownGracePeriod
+	LeaderElectionEnabled = c.LeaderElection.Enabled
+	LeaseName = c.LeaderElection.LeaseName
+	LeaseNamespace = c.LeaderElection.LeaseNamespace
+	LeaseDuration = c.LeaderElection.LeaseDuration
+	RenewDeadline = c.LeaderElection.RenewDeadline
+	RetryPeriod = c.LeaderElection.RetryPeriod
+	BackendType = c.Backend.Type
+	BackendURL = c.Backend.URL
+	BackendTimeout = c.Backend.Timeout
+	WatchNamespaces = c.Watch.Namespaces
+	LabelSelector = c.Watch.LabelSelector
+	FieldSelector = c.Watch.FieldSelector
+	FreezeAllowDeletes = c.Freeze.AllowDeletes
+	FreezeRecheckInterval = c.Freeze.RecheckInterval
+	ValidationPriceMin = c.Validation.PriceMin
+	ValidationPriceMax = c.Validation.PriceMax
+	ValidationCurrencyDecimals = c.Validation.CurrencyDecimals
+	ValidationCategories = c.Validation.Categories
+	ValidationDisplayNamePattern = c.Validation.DisplayNamePattern
+	WebhookAddress = c.Webhook.Address
+	WebhookCertFile = c.Webhook.CertFile
+	WebhookKeyFile = c.Webhook.KeyFile
+}
+
+// splitList splits a comma separated list, ignoring spaces and empty entries
+func splitList(str string) []string {
+	var list []string
+
+	for _, item := range strings.Split(str, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const fixtureConfig = "../fixture/config.yaml"
+
+func TestLoad(t *testing.T) {
+	type args struct {
+		env  map[string]string
+		args []string
+	}
+
+	tests := []struct {
+		name        string
+		args        args
+		wantWorkers int
+		wantResync  time.Duration
+		wantErr     string
+	}{
+		{name: "success config file", args: args{args: []string{"--config", fixtureConfig}}, wantWorkers: 1, wantResync: 15 * time.Minute},
+		{name: "success env over config file", args: args{env: map[string]string{"CONTROLLER_WORKERS": "4"},
+			args: []string{"--config", fixtureConfig}}, wantWorkers: 4, wantResync: 15 * time.Minute},
+		{name: "success flags over env", args: args{env: map[string]string{"CONTROLLER_WORKERS": "4", "CONTROLLER_RESYNC_PERIOD": "1m"},
+			args: []string{"--config", fixtureConfig, "--workers", "8"}}, wantWorkers: 8, wantResync: time.Minute},
+		{name: "failure unknown flag", args: args{args: []string{"--unknown"}}, wantErr: "unknown flag"},
+		{name: "failure missing config file", args: args{args: []string{"--config", "missing.yaml"}}, wantErr: "missing.yaml"},
+		{name: "failure invalid values", args: args{args: []string{"--workers", "0", "--rate-limiter-max-delay", "1ms",
+			"--rate-limiter-base-delay", "1s", "--backend-type", "http", "--finalizer", "bad finalizer"}},
+			wantErr: "workers 0 must be at least 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.args.env {
+				t.Setenv(key, value)
+			}
+
+			got, err := Load(viper.New(), tt.args.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() error = %v, want containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if got.Workers != tt.wantWorkers || got.ResyncPeriod != tt.wantResync {
+				t.Errorf("Load() workers = %d, resync = %s, want %d, %s", got.Workers, got.ResyncPeriod, tt.wantWorkers, tt.wantResync)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() *Config {
+		c, err := Load(viper.New(), nil)
+		if err != nil {
+			t.Fatalf("Load() defaults error = %v", err)
+		}
+
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr []string
+	}{
+		{name: "success defaults", modify: func(c *Config) {}},
+		{name: "success http backend", modify: func(c *Config) { c.Backend.Type, c.Backend.URL = "http", "http://catalog:8080" }},
+		{name: "failure rate limiter", modify: func(c *Config) { c.RateLimiter.MaxDelay, c.RateLimiter.QPS = time.Nanosecond, 0 },
+			wantErr: []string{"max delay", "qps"}},
+		{name: "failure leader election timing", modify: func(c *Config) { c.LeaderElection.LeaseDuration = c.LeaderElection.RenewDeadline },
+			wantErr: []string{"lease duration"}},
+		{name: "failure negative shutdown grace period", modify: func(c *Config) { c.ShutdownGracePeriod = -time.Second },
+			wantErr: []string{"shutdown grace period"}},
+		{name: "success webhook mode", modify: func(c *Config) { c.Mode = ModeWebhook }},
+		{name: "failure unknown mode", modify: func(c *Config) { c.Mode = "operator" }, wantErr: []string{"mode"}},
+		{name: "failure webhook mode without tls", modify: func(c *Config) { c.Mode, c.Webhook.KeyFile = ModeWebhook, "" },
+			wantErr: []string{"webhook cert and key file"}},
+		{name: "failure validation rules", modify: func(c *Config) {
+			c.Validation.PriceMax, c.Validation.CurrencyDecimals, c.Validation.DisplayNamePattern = -1, -1, "[a-"
+		}, wantErr: []string{"validation price max", "validation currency decimals", "validation display name pattern"}},
+		{name: "failure zero reconcile timeout", modify: func(c *Config) { c.ReconcileTimeout = 0 }, wantErr: []string{"reconcile timeout"}},
+		{name: "failure zero max panics", modify: func(c *Config) { c.MaxPanics = 0 }, wantErr: []string{"max panics"}},
+		{name: "success field selector on name and namespace", modify: func(c *Config) {
+			c.Watch.FieldSelector = "metadata.name=testPdt,metadata.namespace!=kube-system"
+		}},
+		{name: "failure field selector on unsupported field", modify: func(c *Config) { c.Watch.FieldSelector = "status.phase=Available" },
+			wantErr: []string{`field selector field "status.phase" is not supported, only metadata.name and metadata.namespace are`}},
+		{name: "failure http backend without url", modify: func(c *Config) { c.Backend.Type = "http" }, wantErr: []string{"backend url"}},
+		{name: "failure every invalid value reported", modify: func(c *Config) {
+			c.QueueName, c.MetricsAddress, c.Watch.LabelSelector = "", "8080", "tier in gold"
+		}, wantErr: []string{"queue name", "metrics address", "label selector"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(c)
+
+			err := c.Validate()
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Apply(t *testing.T) {
+	workers, finalizer := WorkerCount, ProductOperatorFinalizer
+
+	defer func() { WorkerCount, ProductOperatorFinalizer = workers, finalizer }()
+
+	c, err := Load(viper.New(), []string{"--workers", "3", "--finalizer", "test.finalizers.product.estore.com"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	c.Apply()
+
+	if WorkerCount != 3 || ProductOperatorFinalizer != "test.finalizers.product.estore.com" {
+		t.Errorf("Apply() workers = %d, finalizer = %s", WorkerCount, ProductOperatorFinalizer)
+	}
+}
@@ -0,0 +1,163 @@
+// Package config config
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	ccfg "github.com/arutselvan15/estore-common/config"
+)
+
+// Runtime settings of the app section of config.yaml applied while the controller runs, replaced as a whole on
+// reload so readers always see a consistent snapshot
+type Runtime struct {
+	LogLevel            string
+	Freeze              FreezeWindow
+	SystemNamespaces    []string
+	SystemUsers         []string
+	BlacklistNamespaces []string
+	BlacklistUsers      []string
+}
+
+// FreezeWindow app.freeze window, open ended when End is zero and disabled when Start is zero
+type FreezeWindow struct {
+	Start      time.Time
+	End        time.Time
+	Message    string
+	Components []string
+}
+
+var runtimeSettings atomic.Value
+
+// runtimeEnv environment variables of the runtime settings, estore-common only binds them on the global viper
+var runtimeEnv = [][2]string{
+	{"app.log.level", "LOG_LEVEL"},
+	{"app.freeze.startTime", "FREEZE_START_TIME"},
+	{"app.freeze.endTime", "FREEZE_END_TIME"},
+	{"app.freeze.message", "FREEZE_MESSAGE"},
+	{"app.freeze.components", "FREEZE_COMPONENTS"},
+	{"app.system.namespaces", "SYSTEM_NAMESPACES"},
+	{"app.system.users", "SYSTEM_USERS"},
+	{"app.blacklist.namespaces", "BLACKLIST_NAMESPACES"},
+	{"app.blacklist.users", "BLACKLIST_USERS"},
+}
+
+func init() {
+	runtimeSettings.Store(&Runtime{LogLevel: logrus.InfoLevel.String()})
+}
+
+// CurrentRuntime runtime settings in effect
+func CurrentRuntime() *Runtime {
+	return runtimeSettings.Load().(*Runtime)
+}
+
+// SetRuntime replaces the runtime settings in effect
+func SetRuntime(rt *Runtime) {
+	runtimeSettings.Store(rt)
+}
+
+// bindRuntimeEnv binds the environment variables of the runtime settings on v
+func bindRuntimeEnv(v *viper.Viper) error {
+	for _, env := range runtimeEnv {
+		if err := v.BindEnv(env[0], env[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RuntimeFromViper runtime settings held by v, an error is returned for invalid values
+func RuntimeFromViper(v *viper.Viper) (*Runtime, error) {
+	rt := &Runtime{
+		LogLevel:            v.GetString("app.log.level"),
+		SystemNamespaces:    splitList(v.GetString("app.system.namespaces")),
+		SystemUsers:         splitList(v.GetString("app.system.users")),
+		BlacklistNamespaces: splitList(v.GetString("app.blacklist.namespaces")),
+		BlacklistUsers:      splitList(v.GetString("app.blacklist.users")),
+		Freeze: FreezeWindow{
+			Message:    v.GetString("app.freeze.message"),
+			Components: splitList(v.GetString("app.freeze.components")),
+		},
+	}
+
+	if rt.LogLevel == "" {
+		rt.LogLevel = logrus.InfoLevel.String()
+	}
+
+	var errs []string
+
+	if _, err := logrus.ParseLevel(rt.LogLevel); err != nil {
+		errs = append(errs, fmt.Sprintf("log level %q: %v", rt.LogLevel, err))
+	}
+
+	var err error
+
+	if startTime := v.GetString("app.freeze.startTime"); startTime != "" {
+		if rt.Freeze.Start, err = time.Parse(ccfg.TimeLayout, startTime); err != nil {
+			errs = append(errs, fmt.Sprintf("freeze start time %q: %v", startTime, err))
+		}
+	}
+
+	if endTime := v.GetString("app.freeze.endTime"); endTime != "" {
+		if rt.Freeze.End, err = time.Parse(ccfg.TimeLayout, endTime); err != nil {
+			errs = append(errs, fmt.Sprintf("freeze end time %q: %v", endTime, err))
+		}
+	}
+
+	if !rt.Freeze.Start.IsZero() && !rt.Freeze.End.IsZero() && rt.Freeze.End.Before(rt.Freeze.Start) {
+		errs = append(errs, fmt.Sprintf("freeze end time %s is before start time %s", rt.Freeze.End, rt.Freeze.Start))
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration: %s", strings.Join(errs, "; "))
+	}
+
+	return rt, nil
+}
+
+// Enabled true when now is inside the window and the window applies to the component
+func (f FreezeWindow) Enabled(component string, now time.Time) bool {
+	if f.Start.IsZero() || now.Before(f.Start) || (!f.End.IsZero() && now.After(f.End)) {
+		return false
+	}
+
+	for _, c := range f.Components {
+		if c == "all" || c == component {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String window for logs
+func (f FreezeWindow) String() string {
+	if f.Start.IsZero() {
+		return "none"
+	}
+
+	end := "open"
+	if !f.End.IsZero() {
+		end = f.End.Format(ccfg.TimeLayout)
+	}
+
+	return fmt.Sprintf("%s to %s for %s", f.Start.Format(ccfg.TimeLayout), end, strings.Join(f.Components, ","))
+}
+
+// Matches true when value equals or starts with an entry of the list, as the app.system and app.blacklist
+// lists are matched
+func Matches(value string, list []string) bool {
+	for _, entry := range list {
+		if strings.HasPrefix(value, entry) {
+			return true
+		}
+	}
+
+	return false
+}
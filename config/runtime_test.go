@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestRuntimeFromViper(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		wantErr bool
+	}{
+		{name: "success empty", values: map[string]string{}, wantErr: false},
+		{name: "success window", values: map[string]string{"app.log.level": "warn", "app.freeze.startTime": "2020-01-01T21:22:18-08:00",
+			"app.freeze.endTime": "2020-01-02T21:22:18-08:00"}, wantErr: false},
+		{name: "failure log level", values: map[string]string{"app.log.level": "loud"}, wantErr: true},
+		{name: "failure start time", values: map[string]string{"app.freeze.startTime": "tomorrow"}, wantErr: true},
+		{name: "failure end before start", values: map[string]string{"app.freeze.startTime": "2020-01-02T21:22:18-08:00",
+			"app.freeze.endTime": "2020-01-01T21:22:18-08:00"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			for key, value := range tt.values {
+				v.Set(key, value)
+			}
+
+			if _, err := RuntimeFromViper(v); (err != nil) != tt.wantErr {
+				t.Errorf("RuntimeFromViper() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFreezeWindow_Enabled(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		window FreezeWindow
+		want   bool
+	}{
+		{name: "success no window", window: FreezeWindow{Components: []string{"all"}}, want: false},
+		{name: "success inside window", window: FreezeWindow{Start: now.Add(-time.Hour), End: now.Add(time.Hour), Components: []string{"all"}}, want: true},
+		{name: "success open ended window", window: FreezeWindow{Start: now.Add(-time.Hour), Components: []string{Component}}, want: true},
+		{name: "success before window", window: FreezeWindow{Start: now.Add(time.Hour), Components: []string{"all"}}, want: false},
+		{name: "success after window", window: FreezeWindow{Start: now.Add(-time.Hour), End: now.Add(-time.Minute), Components: []string{"all"}}, want: false},
+		{name: "success other component", window: FreezeWindow{Start: now.Add(-time.Hour), Components: []string{"webhook"}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Enabled(Component, now); got != tt.want {
+				t.Errorf("Enabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		list  []string
+		want  bool
+	}{
+		{name: "success exact", value: "default", list: []string{"kube", "default"}, want: true},
+		{name: "success prefix", value: "kube-system", list: []string{"kube", "default"}, want: true},
+		{name: "success no match", value: "shop", list: []string{"kube", "default"}, want: false},
+		{name: "success empty list", value: "shop", list: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.value, tt.list); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package config config
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ApplyFunc applies a reloaded configuration, changes describes every setting that changed
+type ApplyFunc func(conf *Config, rt *Runtime, changes []string)
+
+// Watcher reloads the config file when it changes, usually a mounted ConfigMap. Only valid configurations are
+// applied, an invalid one is rejected and the last good configuration is kept.
+type Watcher struct {
+	path   string
+	args   []string
+	apply  ApplyFunc
+	reject func(err error)
+
+	// last good configuration and the file content it was loaded from
+	conf *Config
+	rt   *Runtime
+	data []byte
+}
+
+// NewWatcher watcher of the config file at path, args are the command line flags the configuration was loaded with
+func NewWatcher(path string, args []string, conf *Config, rt *Runtime, apply ApplyFunc, reject func(err error)) *Watcher {
+	data, _ := ioutil.ReadFile(path)
+
+	return &Watcher{path: path, args: args, apply: apply, reject: reject, conf: conf, rt: rt, data: data}
+}
+
+// Run reloads the config file on every change in its directory until stopCh is closed, the directory is watched
+// as a ConfigMap update replaces the symlink to the file
+func (w *Watcher) Run(stopCh <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(w.path)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if err := w.Reload(); err != nil {
+				w.reject(err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			w.reject(fmt.Errorf("watching config file %s failed: %v", w.path, err))
+		}
+	}
+}
+
+// Reload loads the config file and applies it when its content changed and it is valid
+func (w *Watcher) Reload() error {
+	data, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return fmt.Errorf("reading config file %s failed: %v", w.path, err)
+	}
+
+	if bytes.Equal(data, w.data) {
+		return nil
+	}
+
+	// the environment still takes precedence over the file
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	if err := bindRuntimeEnv(v); err != nil {
+		return err
+	}
+
+	if err := v.ReadConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("parsing config file %s failed: %v", w.path, err)
+	}
+
+	// the flags given on start still take precedence over the file
+	conf, err := Load(v, withoutConfigFile(w.args))
+	if err != nil {
+		return err
+	}
+
+	rt, err := RuntimeFromViper(v)
+	if err != nil {
+		return err
+	}
+
+	changes := Changes(w.conf, w.rt, conf, rt)
+
+	w.conf, w.rt, w.data = conf, rt, data
+
+	if len(changes) > 0 {
+		w.apply(conf, rt, changes)
+	}
+
+	return nil
+}
+
+// Changes describes the settings that differ between the configurations
+func Changes(oldConf *Config, oldRt *Runtime, conf *Config, rt *Runtime) []string {
+	var changes []string
+
+	change := func(name string, old, value interface{}) {
+		if !reflect.DeepEqual(old, value) {
+			changes = append(changes, fmt.Sprintf("%s changed from %v to %v", name, old, value))
+		}
+	}
+
+	change("workers", oldConf.Workers, conf.Workers)
+	change("log level", oldRt.LogLevel, rt.LogLevel)
+	change("freeze window", oldRt.Freeze.String(), rt.Freeze.String())
+	change("freeze message", oldRt.Freeze.Message, rt.Freeze.Message)
+	change("system namespaces", oldRt.SystemNamespaces, rt.SystemNamespaces)
+	change("system users", oldRt.SystemUsers, rt.SystemUsers)
+	change("blacklist namespaces", oldRt.BlacklistNamespaces, rt.BlacklistNamespaces)
+	change("blacklist users", oldRt.BlacklistUsers, rt.BlacklistUsers)
+
+	// everything else is wired on start
+	oldRest, rest := *oldConf, *conf
+	oldRest.Workers, rest.Workers = 0, 0
+
+	if !reflect.DeepEqual(oldRest, rest) {
+		changes = append(changes, "controller settings other than workers changed, they apply on restart")
+	}
+
+	return changes
+}
+
+// withoutConfigFile args without the config file flag, the file is read by the watcher
+func withoutConfigFile(args []string) []string {
+	var filtered []string
+
+	for i := 0; i < len(args); i++ {
+		switch arg := strings.TrimLeft(args[i], "-"); {
+		case arg == ConfigFileFlag && len(args[i]) > len(arg):
+			i++
+		case strings.HasPrefix(arg, ConfigFileFlag+"=") && len(args[i]) > len(arg):
+		default:
+			filtered = append(filtered, args[i])
+		}
+	}
+
+	return filtered
+}
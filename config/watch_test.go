@@ -0,0 +1,155 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const watchConfig = `app:
+  log:
+    level: %s
+  blacklist:
+    namespaces: virus
+controller:
+  workers: %s
+`
+
+func writeConfig(t *testing.T, path, level, workers string) {
+	if err := ioutil.WriteFile(path, []byte(strings.Replace(strings.Replace(watchConfig, "%s", level, 1), "%s", workers, 1)), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+// newTestWatcher watcher of a config file in a temp dir, applied configurations are sent on the returned channel
+func newTestWatcher(t *testing.T, args []string) (*Watcher, string, chan []string) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "debug", "1")
+
+	// as the global viper estore-common binds the environment on
+	v := viper.New()
+	if err := bindRuntimeEnv(v); err != nil {
+		t.Fatalf("bindRuntimeEnv() error = %v", err)
+	}
+
+	conf, err := Load(v, append([]string{"--config", path}, args...))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	rt, err := RuntimeFromViper(v)
+	if err != nil {
+		t.Fatalf("RuntimeFromViper() error = %v", err)
+	}
+
+	applied := make(chan []string, 10)
+	w := NewWatcher(path, append([]string{"--config", path}, args...), conf, rt, func(conf *Config, rt *Runtime, changes []string) {
+		applied <- changes
+	}, func(err error) {})
+
+	return w, path, applied
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		level       string
+		workers     string
+		wantErr     bool
+		wantChanges []string
+	}{
+		{name: "success unchanged", level: "debug", workers: "1"},
+		{name: "success log level and workers", level: "info", workers: "3",
+			wantChanges: []string{"workers changed from 1 to 3", "log level changed from debug to info"}},
+		{name: "success flag keeps precedence", args: []string{"--workers", "2"}, level: "debug", workers: "3"},
+		{name: "failure invalid workers", level: "debug", workers: "0", wantErr: true},
+		{name: "failure invalid log level", level: "loud", workers: "1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, path, applied := newTestWatcher(t, tt.args)
+			conf := w.conf
+
+			writeConfig(t, path, tt.level, tt.workers)
+
+			if err := w.Reload(); (err != nil) != tt.wantErr {
+				t.Fatalf("Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && w.conf != conf {
+				t.Errorf("Reload() replaced the last good config with an invalid one")
+			}
+
+			var changes []string
+			if len(applied) > 0 {
+				changes = <-applied
+			}
+
+			if strings.Join(changes, ",") != strings.Join(tt.wantChanges, ",") {
+				t.Errorf("Reload() changes = %v, want %v", changes, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestWatcher_Reload_envOverride(t *testing.T) {
+	t.Setenv("BLACKLIST_NAMESPACES", "malware")
+
+	w, path, applied := newTestWatcher(t, nil)
+
+	writeConfig(t, path, "info", "1")
+
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	if len(applied) != 1 {
+		t.Fatalf("Reload() applied %d configs, want 1", len(applied))
+	}
+
+	if w.rt.LogLevel != "info" || strings.Join(w.rt.BlacklistNamespaces, ",") != "malware" {
+		t.Errorf("Reload() log level = %v, blacklist namespaces = %v, want info, [malware]", w.rt.LogLevel, w.rt.BlacklistNamespaces)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	w, path, applied := newTestWatcher(t, nil)
+
+	stopCh := make(chan struct{})
+	runDone := make(chan error)
+
+	go func() { runDone <- w.Run(stopCh) }()
+
+	// the file is written until the watcher picks it up, it may not be watching yet
+	deadline := time.After(5 * time.Second)
+
+	for done := false; !done; {
+		writeConfig(t, path, "info", "4")
+
+		select {
+		case changes := <-applied:
+			if len(changes) != 2 {
+				t.Errorf("Run() changes = %v, want workers and log level", changes)
+			}
+
+			done = true
+		case <-time.After(50 * time.Millisecond):
+			_ = os.Chtimes(path, time.Now(), time.Now())
+		case <-deadline:
+			t.Fatalf("Run() config change not applied")
+		}
+	}
+
+	close(stopCh)
+
+	if err := <-runDone; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
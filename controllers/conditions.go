@@ -0,0 +1,89 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"time"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+)
+
+// condition types maintained by the controller
+const (
+	// ConditionTypeReady product is synced and available
+	ConditionTypeReady pdtv1.ProductConditionType = "Ready"
+	// ConditionTypeSynced product spec is mirrored to the backend
+	ConditionTypeSynced pdtv1.ProductConditionType = "Synced"
+	// ConditionTypeBackendReachable last call to the backend succeeded
+	ConditionTypeBackendReachable pdtv1.ProductConditionType = "BackendReachable"
+	// ConditionTypeDeleting product is being removed from the backend
+	ConditionTypeDeleting pdtv1.ProductConditionType = "Deleting"
+	// ConditionTypeFinalizer operator finalizer is present, so deletion waits for the backend cleanup
+	ConditionTypeFinalizer pdtv1.ProductConditionType = "Finalizer"
+	// ConditionTypeBlocked product namespace or requester is blacklisted, so it is not reconciled
+	ConditionTypeBlocked pdtv1.ProductConditionType = "Blocked"
+	// ConditionTypeFailed product exhausted its retries and is no longer retried until it changes
+	ConditionTypeFailed pdtv1.ProductConditionType = "Failed"
+	// ConditionTypeFrozen reconcile is deferred until the freeze window closes
+	ConditionTypeFrozen pdtv1.ProductConditionType = "Frozen"
+	// ConditionTypeInvalid product spec breaks a validation rule, so it is not reconciled
+	ConditionTypeInvalid pdtv1.ProductConditionType = "Invalid"
+)
+
+// condition reasons
+const (
+	ReasonAvailable            = "Available"
+	ReasonSynced               = "Synced"
+	ReasonSyncFailed           = "SyncFailed"
+	ReasonReachable            = "Reachable"
+	ReasonBackendError         = "BackendError"
+	ReasonDeleting             = "Deleting"
+	ReasonDeleteFailed         = "DeleteFailed"
+	ReasonFinalizerPending     = "FinalizerPending"
+	ReasonFinalizerAdded       = "FinalizerAdded"
+	ReasonBlacklistedNamespace = "BlacklistedNamespace"
+	ReasonBlacklistedUser      = "BlacklistedUser"
+	ReasonAllowed              = "Allowed"
+	ReasonFreezeWindow         = "FreezeWindow"
+	ReasonFreezeOver           = "FreezeOver"
+	ReasonRetriesExhausted     = "RetriesExhausted"
+	ReasonPermanentError       = "PermanentError"
+	ReasonQuarantined          = "Quarantined"
+	ReasonTimeout              = "Timeout"
+	ReasonValidationFailed     = "ValidationFailed"
+	ReasonValid                = "Valid"
+)
+
+// getCondition returns the condition of the type or nil
+func getCondition(status *pdtv1.ProductStatus, condType pdtv1.ProductConditionType) *pdtv1.ProductCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// setCondition adds or updates the condition of the type, the transition time only changes with the status
+func setCondition(status *pdtv1.ProductStatus, condType pdtv1.ProductConditionType, condStatus pdtv1.ConditionStatus,
+	reason, message string) {
+	if cond := getCondition(status, condType); cond != nil {
+		if cond.Status != condStatus {
+			cond.Status = condStatus
+			cond.LastTransitionTime = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		cond.Reason = reason
+		cond.Message = message
+
+		return
+	}
+
+	status.Conditions = append(status.Conditions, pdtv1.ProductCondition{
+		Type:               condType,
+		Status:             condStatus,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: time.Now().UTC().Format(time.RFC3339),
+	})
+}
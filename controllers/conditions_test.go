@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"testing"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+)
+
+func Test_setCondition(t *testing.T) {
+	status := &pdtv1.ProductStatus{}
+
+	setCondition(status, ConditionTypeReady, pdtv1.ConditionFalse, ReasonSyncFailed, "failed")
+
+	cond := getCondition(status, ConditionTypeReady)
+	if cond == nil || cond.Status != pdtv1.ConditionFalse || cond.LastTransitionTime == "" {
+		t.Fatalf("setCondition() add = %+v", cond)
+	}
+
+	// same status keeps the transition time
+	cond.LastTransitionTime = "keep"
+	setCondition(status, ConditionTypeReady, pdtv1.ConditionFalse, ReasonBackendError, "still failing")
+
+	if cond = getCondition(status, ConditionTypeReady); cond.LastTransitionTime != "keep" || cond.Reason != ReasonBackendError {
+		t.Errorf("setCondition() same status = %+v, want transition time kept and reason updated", cond)
+	}
+
+	// status change moves the transition time
+	setCondition(status, ConditionTypeReady, pdtv1.ConditionTrue, ReasonAvailable, "available")
+
+	if cond = getCondition(status, ConditionTypeReady); cond.LastTransitionTime == "keep" || cond.Status != pdtv1.ConditionTrue {
+		t.Errorf("setCondition() status change = %+v, want new transition time", cond)
+	}
+
+	if len(status.Conditions) != 1 {
+		t.Errorf("setCondition() conditions = %v, want 1", len(status.Conditions))
+	}
+
+	if getCondition(status, ConditionTypeSynced) != nil {
+		t.Errorf("getCondition() missing type want nil")
+	}
+}
@@ -2,9 +2,17 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
@@ -14,60 +22,224 @@ import (
 	cc "github.com/arutselvan15/estore-common/clients"
 	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
 	pdtv1Informers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions/estore/v1"
+	pdtv1Listers "github.com/arutselvan15/estore-product-kube-client/pkg/client/listers/estore/v1"
+	lc "github.com/arutselvan15/go-utils/logconstants"
 
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
 	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
 	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
+	"github.com/arutselvan15/estore-product-kube-controller/metrics"
 )
 
 var log = gLog.GetLogger()
 
+var (
+	// ErrNotReady returned by Ready until the workers are running on a synced cache
+	ErrNotReady = errors.New("product cache not synced or workers not running")
+	// ErrWorkersStalled returned by Healthy when no worker pulled from a non empty queue within the window
+	ErrWorkersStalled = errors.New("product workers stalled")
+)
+
 // Controller controller
 type Controller struct {
-	pdtInformer     cache.SharedIndexInformer
+	pdtLister       pdtv1Listers.ProductLister
 	pdtListerSynced cache.InformerSynced
 	pdtQueue        workqueue.RateLimitingInterface
+	scope           Scope
 	clients         cc.EstoreClientInterface
+	backend         backend.ProductBackend
 	recorder        record.EventRecorder
 	processItem     ProcessItemType
+
+	// running set to 1 once the cache is synced and the workers are started, accessed atomically
+	running int32
+	// lastPull unix nano time a worker last pulled from the queue, accessed atomically
+	lastPull int64
+
+	// deleted last known state of products removed from the cache, keyed by namespace/name until the key is forgotten
+	deleted sync.Map
+	// freshRead keys to read from the api server instead of the cache on their next sync
+	freshRead sync.Map
+	// correlations correlation ids of the queued keys, kept across retries until a reconcile reaches an outcome
+	correlations sync.Map
+	// quarantine keys whose reconciles panicked too often in a row, they are not reconciled until released
+	quarantine quarantine
+
+	// workersMu guards workerStops, one stop channel per running worker
+	workersMu   sync.Mutex
+	workerStops []chan struct{}
+	// workers running worker goroutines, waited for on shutdown
+	workers sync.WaitGroup
+	// inFlight keys being reconciled with the time their reconcile started
+	inFlight sync.Map
+	// reconcileCtx parent of the reconcile contexts, cancelled once the shutdown grace period expired
+	reconcileCtx     context.Context
+	cancelReconciles context.CancelFunc
 }
 
-// NewController new controller
-func NewController(pdtInformer pdtv1Informers.ProductInformer, pdtQueue workqueue.RateLimitingInterface,
-	clients cc.EstoreClientInterface, recorder record.EventRecorder, processItem ProcessItemType) *Controller {
+// NewController new controller for the product informers of the scope, one informer per watched namespace
+// or a single informer when all namespaces are watched
+func NewController(pdtInformers []pdtv1Informers.ProductInformer, scope Scope, pdtQueue workqueue.RateLimitingInterface,
+	clients cc.EstoreClientInterface, pdtBackend backend.ProductBackend, recorder record.EventRecorder,
+	processItem ProcessItemType) *Controller {
 	c := &Controller{
-		pdtInformer:     pdtInformer.Informer(),
-		pdtListerSynced: pdtInformer.Informer().HasSynced,
+		pdtLister:       newScopedLister(pdtInformers, scope.Namespaces),
+		pdtListerSynced: informersSynced(pdtInformers),
 		pdtQueue:        pdtQueue,
+		scope:           scope,
 		clients:         clients,
+		backend:         pdtBackend,
 		recorder:        recorder,
 		processItem:     processItem,
 	}
 
-	pdtInformer.Informer().AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				if key := onAdd(obj.(*pdtv1.Product), c.recorder); key != "" {
-					c.pdtQueue.Add(key)
-				}
-			},
-			UpdateFunc: func(oldObj, newObj interface{}) {
-				if key := onUpdate(oldObj.(*pdtv1.Product), newObj.(*pdtv1.Product), c.recorder); key != "" {
-					c.pdtQueue.Add(key)
-				}
-			},
-			DeleteFunc: func(obj interface{}) {
-				if key := onDelete(obj.(*pdtv1.Product), c.recorder); key != "" {
-					c.pdtQueue.Add(key)
-				}
+	for _, pdtInformer := range pdtInformers {
+		pdtInformer.Informer().AddEventHandler(
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					pdt := obj.(*pdtv1.Product)
+					if !c.scope.Contains(pdt) {
+						return
+					}
+
+					id := gLog.NewCorrelationID()
+					logger := gLog.WithCorrelationID(id)
+
+					if key := onAdd(pdt, c.recorder, logger); key != "" {
+						c.enqueue(key, id, logger)
+					}
+				},
+				UpdateFunc: func(oldObj, newObj interface{}) {
+					pdt := newObj.(*pdtv1.Product)
+					if !c.scope.Contains(pdt) {
+						return
+					}
+
+					id := gLog.NewCorrelationID()
+					logger := gLog.WithCorrelationID(id)
+
+					if key := c.releaseQuarantined(pdt, logger); key != "" {
+						c.enqueue(key, id, logger)
+						return
+					}
+
+					if key := onUpdate(oldObj.(*pdtv1.Product), pdt, c.recorder, logger); key != "" {
+						c.enqueue(key, id, logger)
+					}
+				},
+				DeleteFunc: c.handleDelete,
 			},
-		},
-	)
+		)
+	}
 
 	return c
 }
 
-// Run run the controller with number of workers mentioned in the arguments
-func (c *Controller) Run(workerCount int, stopCh <-chan struct{}) {
+// handleDelete enqueues deleted products, including the ones wrapped in a tombstone when the watch missed the delete
+func (c *Controller) handleDelete(obj interface{}) {
+	pdt, ok := obj.(*pdtv1.Product)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			runtime.HandleError(fmt.Errorf("unexpected object of type %T on delete", obj))
+			return
+		}
+
+		if pdt, ok = tombstone.Obj.(*pdtv1.Product); !ok {
+			runtime.HandleError(fmt.Errorf("unexpected tombstone object of type %T on delete", tombstone.Obj))
+			return
+		}
+	}
+
+	// a product leaving the label scope is deleted from the informer, but still exists
+	if !c.scope.Contains(pdt) {
+		return
+	}
+
+	id := gLog.NewCorrelationID()
+	logger := gLog.WithCorrelationID(id)
+
+	if key := onDelete(pdt, c.recorder, logger); key != "" {
+		// keep the final state for the backend cleanup, the product is gone from the cache
+		c.deleted.Store(key, pdt)
+		c.enqueue(key, id, logger)
+	}
+}
+
+// releaseQuarantined releases the key of a quarantined product whose release annotation was set to a new value,
+// returns the released key or an empty one
+func (c *Controller) releaseQuarantined(pdt *pdtv1.Product, logger gLog.Logger) string {
+	key, err := cache.MetaNamespaceKeyFunc(pdt)
+	if err != nil || !c.quarantine.release(key, pdt) {
+		return ""
+	}
+
+	c.recorder.Event(pdt, corev1.EventTypeNormal, "Quarantine", "Released")
+	logger.SetObjectName(pdt.Name).SetObjectState(lc.Received).Infof("product %s released from quarantine", key)
+
+	return key
+}
+
+// enqueue adds the key of an event with the correlation id of the event. The reconciles of the key log the id
+// until one of them reaches an outcome, a later event of the key supersedes it.
+func (c *Controller) enqueue(key, id string, logger gLog.Logger) {
+	if previous, ok := c.correlations.Swap(key, id); ok {
+		logger.Debugf("correlation %s of product %s superseded", previous, key)
+	}
+
+	c.pdtQueue.Add(key)
+}
+
+// forget ends the work on the key once a reconcile reached an outcome, dropping its retry history, its
+// correlation id and the final state kept for its cleanup
+func (c *Controller) forget(key, id string) {
+	c.pdtQueue.Forget(key)
+	c.correlations.CompareAndDelete(key, id)
+	c.deleted.Delete(key)
+}
+
+// correlationID correlation id of the reconciles of the key, a new one for keys queued without an event
+func (c *Controller) correlationID(key string) string {
+	id, _ := c.correlations.LoadOrStore(key, gLog.NewCorrelationID())
+
+	return id.(string)
+}
+
+// RequeueNotReady enqueues the products that are not ready or are frozen. Resyncs of such products are not
+// admitted, so changed runtime settings such as the blacklists or the freeze window only reach them this way.
+func (c *Controller) RequeueNotReady() {
+	pdts, err := c.pdtLister.List(labels.Everything())
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("listing products to requeue failed: %v", err))
+		return
+	}
+
+	for _, pdt := range pdts {
+		if !c.scope.Contains(pdt) || !notReady(pdt) {
+			continue
+		}
+
+		key, err := cache.MetaNamespaceKeyFunc(pdt)
+		if err != nil {
+			continue
+		}
+
+		id := gLog.NewCorrelationID()
+		logger := gLog.WithCorrelationID(id)
+		logger.SetObjectName(pdt.Name).Debugf("product %s requeued for the changed runtime settings", key)
+		c.enqueue(key, id, logger)
+	}
+}
+
+// notReady true when the product is not ready or is frozen
+func notReady(pdt *pdtv1.Product) bool {
+	ready, frozen := getCondition(&pdt.Status, ConditionTypeReady), getCondition(&pdt.Status, ConditionTypeFrozen)
+
+	return ready == nil || ready.Status != pdtv1.ConditionTrue || (frozen != nil && frozen.Status == pdtv1.ConditionTrue)
+}
+
+// Run runs the controller with workerCount workers until ctx is done, then drains the reconciles in flight
+func (c *Controller) Run(ctx context.Context, workerCount int) {
 	// don't let panics crash the process
 	defer runtime.HandleCrash()
 
@@ -76,69 +248,390 @@ func (c *Controller) Run(workerCount int, stopCh <-chan struct{}) {
 
 	// waits for caches to populate.  It returns true if it was successful, false if the controller should shutdown
 	// wait for the caches to synchronize before starting the workers
-	if !cache.WaitForNamedCacheSync(fmt.Sprintf("%s-%s", cfg.ResourceName, cfg.Component), stopCh, c.pdtListerSynced) {
+	if !cache.WaitForNamedCacheSync(fmt.Sprintf("%s-%s", cfg.ResourceName, cfg.Component), ctx.Done(), c.pdtListerSynced) {
 		log.Error("timed out waiting for caches to sync product resource")
 		return
 	}
 
-	// launch worker(s) to process the resources
-	for i := 0; i < workerCount; i++ {
-		go wait.Until(c.runWorker, time.Second, stopCh)
+	atomic.StoreInt64(&c.lastPull, time.Now().UnixNano())
+
+	// launch worker(s) to process the resources, running is switched together with the workers so SetWorkers
+	// never starts a worker outside of Run
+	c.workersMu.Lock()
+	c.reconcileCtx, c.cancelReconciles = context.WithCancel(context.Background())
+	atomic.StoreInt32(&c.running, 1)
+	c.scaleWorkers(workerCount)
+	c.workersMu.Unlock()
+
+	defer c.cancelReconciles()
+
+	<-ctx.Done()
+
+	c.drain(cfg.ShutdownGracePeriod)
+}
+
+// drain stops the workers from taking new keys and waits up to gracePeriod for the reconciles in flight to
+// finish, the ones still running afterwards are logged as abandoned and cancelled
+func (c *Controller) drain(gracePeriod time.Duration) {
+	c.workersMu.Lock()
+	atomic.StoreInt32(&c.running, 0)
+	c.scaleWorkers(0)
+	c.workersMu.Unlock()
+
+	// wakes the workers waiting for a key, queued keys are listed again on the next start
+	c.pdtQueue.ShutDown()
+
+	drained := make(chan struct{})
+
+	go func() {
+		c.workers.Wait()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-drained:
+		log.Infof("shutdown drained the in-flight reconciles, %d queued products left", c.pdtQueue.Len())
+	case <-timer.C:
+		c.inFlight.Range(func(key, started interface{}) bool {
+			log.Warnf("shutdown grace period %s expired, abandoned reconcile of product %s running for %s",
+				gracePeriod, key, time.Since(started.(time.Time)).Round(time.Millisecond))
+			return true
+		})
+
+		c.cancelReconciles()
+	}
+}
+
+// SetWorkers grows or shrinks the running workers to workerCount, a removed worker finishes its current product
+// first. Ignored while the controller is not running.
+func (c *Controller) SetWorkers(workerCount int) {
+	c.workersMu.Lock()
+	defer c.workersMu.Unlock()
+
+	if atomic.LoadInt32(&c.running) == 0 {
+		return
+	}
+
+	c.scaleWorkers(workerCount)
+}
+
+// Workers number of running workers
+func (c *Controller) Workers() int {
+	c.workersMu.Lock()
+	defer c.workersMu.Unlock()
+
+	return len(c.workerStops)
+}
+
+// scaleWorkers starts or stops workers until workerCount are running, workersMu must be held and Run must have
+// set the reconcile context
+func (c *Controller) scaleWorkers(workerCount int) {
+	for len(c.workerStops) < workerCount {
+		stopCh := make(chan struct{})
+		c.workerStops = append(c.workerStops, stopCh)
+		c.workers.Add(1)
+
+		go func() {
+			defer c.workers.Done()
+			wait.Until(func() { c.runWorker(c.reconcileCtx, stopCh) }, time.Second, stopCh)
+		}()
+	}
+
+	for len(c.workerStops) > workerCount && len(c.workerStops) > 0 {
+		last := len(c.workerStops) - 1
+		close(c.workerStops[last])
+		c.workerStops = c.workerStops[:last]
+	}
+}
+
+// Lister lister of the products in scope
+func (c *Controller) Lister() pdtv1Listers.ProductLister {
+	return c.pdtLister
+}
+
+// Ready returns ErrNotReady unless the product cache is synced and the workers are running
+func (c *Controller) Ready() error {
+	if atomic.LoadInt32(&c.running) == 0 {
+		return ErrNotReady
+	}
+
+	return nil
+}
+
+// Healthy returns ErrWorkersStalled when the queue is not empty and no worker pulled from it within window
+func (c *Controller) Healthy(window time.Duration) error {
+	// workers are not expected to pull before they are started
+	if atomic.LoadInt32(&c.running) == 0 || c.pdtQueue.Len() == 0 {
+		return nil
+	}
+
+	if since := time.Since(time.Unix(0, atomic.LoadInt64(&c.lastPull))); since > window {
+		return fmt.Errorf("%w: queue length %d, last pull %s ago", ErrWorkersStalled, c.pdtQueue.Len(), since.Round(time.Second))
 	}
 
-	<-stopCh
+	return nil
 }
 
-func (c *Controller) runWorker() {
-	for c.processNextItem() {
+func (c *Controller) runWorker(ctx context.Context, stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+
+		if !c.processNextItem(ctx) {
+			return
+		}
 	}
 }
 
-func (c *Controller) processNextItem() bool {
+func (c *Controller) processNextItem(ctx context.Context) bool {
 	// pull the next work item from queue.  It should be a key we use to lookup something in a cache
 	key, shutdown := c.pdtQueue.Get()
 	if shutdown {
 		return false
 	}
 
+	atomic.StoreInt64(&c.lastPull, time.Now().UnixNano())
+
+	c.inFlight.Store(key, time.Now())
+	defer c.inFlight.Delete(key)
+
 	// tell the queue that we are done with processing this key. This unblocks the key for other workers
 	// this allows safe parallel processing because two pods with the same key are never processed in parallel.
 	defer c.pdtQueue.Done(key)
 
-	err := c.doSync(key.(string))
-	if err != nil {
+	// every reconcile logs with a logger of its own, carried through the reconcile by ctx
+	id := c.correlationID(key.(string))
+	logger := gLog.WithCorrelationID(id)
+	logger.SetObjectName(key.(string)).SetStep(cfg.ProcessItem)
+
+	// a quarantined key is dropped until its product is released
+	if c.quarantine.contains(key.(string)) {
+		c.forget(key.(string), id)
+		logger.Debugf("product %s quarantined, not reconciled", key)
+
+		return true
+	}
+
+	reconciled, err := c.syncRecovered(withRetries(gLog.NewContext(ctx, logger), c.pdtQueue.NumRequeues(key)), key.(string))
+	if err == nil {
+		c.quarantine.recordSuccess(key.(string))
+	}
+
+	var (
+		panicErr *PanicError
+		result   string
+	)
+
+	switch {
+	case err == nil && reconciled.RequeueAfter > 0:
+		// not a failure, the requeue does not grow the rate limiter backoff
+		c.pdtQueue.Forget(key)
+		c.pdtQueue.AddAfter(key, reconciled.RequeueAfter)
+		result = metrics.ResultRequeue
+	case err == nil && reconciled.Requeue:
+		c.pdtQueue.Forget(key)
+		c.pdtQueue.Add(key)
+		result = metrics.ResultRequeue
+	case errors.Is(err, context.Canceled):
+		// cancelled on shutdown, the key is neither retried nor given up on, the next leader lists it again
+		logger.SetStepState(lc.Skip).Debugf("reconcile of product %s cancelled on shutdown", key)
+		result = metrics.ResultError
+	case err == nil:
+		// forget about the #AddRateLimited history of the key on every successful synchronization.
+		// this ensures that future processing of updates for this key is not delayed because of
+		// an outdated error history.
+		c.forget(key.(string), id)
+		result = metrics.ResultSuccess
+	case errors.As(err, &panicErr):
+		// one product must not take the controller down, a key panicking again and again is quarantined
+		logger.SetStepState(lc.Error).Errorf("reconcile of product %s panicked: %v\n%s", key, panicErr.Value, panicErr.Stack)
+
+		if c.quarantine.recordPanic(key.(string), panicErr, c.releaseValue(key.(string))) {
+			c.giveUp(logger, key.(string), ReasonQuarantined, fmt.Sprintf("quarantined after %d panics in a row, set annotation %s to a new value to release: %v",
+				cfg.MaxPanics, cfg.ReleaseQuarantineAnnotation, panicErr.Value))
+			c.forget(key.(string), id)
+			result = metrics.ResultQuarantined
+		} else {
+			c.pdtQueue.AddRateLimited(key)
+			result = metrics.ResultPanic
+		}
+	case errorKind(err) == ErrorKindPermanent:
+		// retrying does not help, the key is processed again once its product changes
+		c.giveUp(logger, key.(string), ReasonPermanentError, fmt.Sprintf("not retried: %v", err))
+		c.forget(key.(string), id)
+		result = metrics.ResultDropped
+	case cfg.MaxRetries > 0 && c.pdtQueue.NumRequeues(key) >= cfg.MaxRetries:
+		// a key failing on every retry is given up on, it is processed again once its product changes
+		c.giveUp(logger, key.(string), ReasonRetriesExhausted, fmt.Sprintf("giving up after %d retries: %v", c.pdtQueue.NumRequeues(key), err))
+		c.forget(key.(string), id)
+		result = metrics.ResultDropped
+	case errorKind(err) == ErrorKindConflict:
+		// the product changed since it was read, retry with the product read from the api server. Conflicts
+		// count as retries, a key conflicting on every write backs off and is given up on like any other.
+		c.freshRead.Store(key, true)
+		c.pdtQueue.AddRateLimited(key)
+		logger.SetStepState(lc.Retry).Debugf("doSync conflicted for key %s, re-queued with a fresh read: %v", key, err)
+		result = metrics.ResultRequeue
+	case errorKind(err) == ErrorKindTimeout:
+		// a hung backend or api call, retried with backoff like any transient error
+		c.pdtQueue.AddRateLimited(key)
+		runtime.HandleError(fmt.Errorf("doSync timed out for key %s after %s, correlation %s, error: %v", key, cfg.ReconcileTimeout, id, err))
+		result = metrics.ResultTimeout
+	default:
 		// re-enqueue the key rate limited. Based on the rate limiter on the
 		// queue and the re-enqueue history, the key will be processed later again.
 		// you can custom logic here to take decision to re-process the item or not
 		c.pdtQueue.AddRateLimited(key)
-		runtime.HandleError(fmt.Errorf("doSync failed for key %s, error: %v", key, err))
-	} else {
-		// forget about the #AddRateLimited history of the key on every successful synchronization.
-		// this ensures that future processing of updates for this key is not delayed because of
-		// an outdated error history.
-		c.pdtQueue.Forget(key)
+		runtime.HandleError(fmt.Errorf("doSync failed for key %s, correlation %s, error: %v", key, id, err))
+		result = metrics.ResultError
 	}
 
+	metrics.ObserveReconcile(result)
+	logger.Debugf("reconcile of product %s finished with result %s", key, result)
+
 	return true
 }
 
-func (c *Controller) doSync(key string) error {
-	pdt := &pdtv1.Product{}
+// syncRecovered doSync recovering a panic of the reconcile into a PanicError
+func (c *Controller) syncRecovered(ctx context.Context, key string) (result Result, err error) {
+	defer recoverPanic(&err)
+
+	return c.doSync(ctx, key)
+}
 
-	obj, exists, err := c.pdtInformer.GetIndexer().GetByKey(key)
+// releaseValue value of the release annotation of the cached product of the key
+func (c *Controller) releaseValue(key string) string {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
-		log.Errorf("fetching object with key %s from cache failed with %v", key, err)
+		return ""
+	}
 
-		return err
+	pdt, err := c.pdtLister.Products(namespace).Get(name)
+	if err != nil {
+		return ""
 	}
 
-	if !exists {
-		return nil
+	return pdt.Annotations[cfg.ReleaseQuarantineAnnotation]
+}
+
+// doSync reconciles the product of the key within cfg.ReconcileTimeout
+func (c *Controller) doSync(ctx context.Context, key string) (Result, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		// an invalid key never becomes valid, do not retry it
+		runtime.HandleError(fmt.Errorf("invalid product key %s: %v", key, err))
+		return Result{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ReconcileTimeout)
+	defer cancel()
+
+	pdt, err := c.pdtLister.Products(namespace).Get(name)
+	if _, fresh := c.freshRead.LoadAndDelete(key); fresh {
+		// the cached product is older than the one a write conflicted with
+		pdt, err = c.clients.GetProductClient().EstoreV1().Products(namespace).Get(name, metav1.GetOptions{})
+	} else if apierrors.IsNotFound(err) && cfg.LiveGetFallback {
+		// the cache may lag behind, confirm with the api server before treating the product as gone
+		pdt, err = c.clients.GetProductClient().EstoreV1().Products(namespace).Get(name, metav1.GetOptions{})
+	}
+
+	if apierrors.IsNotFound(err) {
+		return c.cleanupGone(ctx, key, namespace, name)
+	}
+
+	if err != nil {
+		gLog.FromContext(ctx).Errorf("fetching object with key %s failed with %v", key, err)
+
+		return Result{}, err
+	}
+
+	// the live get is not filtered by the scope
+	if !c.scope.Contains(pdt) {
+		return Result{}, nil
+	}
+
+	start := time.Now()
+	reconciled, err := c.processItem(ctx, pdt, c.clients, c.backend, c.recorder)
+
+	result := metrics.ResultSuccess
+	if errorKind(err) == ErrorKindTimeout {
+		result = metrics.ResultTimeout
+	} else if err != nil {
+		result = metrics.ResultError
+	} else if !reconciled.IsZero() {
+		result = metrics.ResultRequeue
+	}
+
+	metrics.ObserveProcessItem(result, time.Since(start))
+
+	return reconciled, err
+}
+
+// cleanupGone removes a product that no longer exists from the backend, so a delete is never dropped even
+// when the finalizer was missing. Deleting a product missing in the backend is not an error. Like any delete
+// the cleanup waits for the freeze window unless deletes are allowed, the final state is kept until then.
+func (c *Controller) cleanupGone(ctx context.Context, key, namespace, name string) (Result, error) {
+	logger := gLog.FromContext(ctx)
+
+	pdt := &pdtv1.Product{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	if obj, ok := c.deleted.Load(key); ok {
+		pdt = obj.(*pdtv1.Product).DeepCopy()
+	}
+
+	// the product is gone, so the deferral is only logged
+	if isFrozen, message := frozen(); isFrozen && !freezeExempt(pdt) && !cfg.FreezeAllowDeletes {
+		result := Result{RequeueAfter: freezeRequeueAfter()}
+		logger.SetObjectState(lc.Ignored).SetStepState(lc.Skip).Infof("product %s gone, backend cleanup deferred for %s, freeze: %s",
+			key, result.RequeueAfter, message)
+
+		return result, nil
+	}
+
+	logger.SetObjectState(lc.Deleting).SetStep(cfg.ProcessItem).SetStepState(lc.Start).Infof("product %s gone, cleaning up backend", key)
+
+	if err := delete(ctx, pdt, c.backend, c.recorder); err != nil {
+		return Result{}, err
+	}
+
+	c.deleted.Delete(key)
+
+	return Result{}, nil
+}
+
+// giveUp marks the product of a key dropped out of the queue with a terminal Failed condition, the status
+// update is best effort as the key is not retried anyway
+func (c *Controller) giveUp(logger gLog.Logger, key, reason, message string) {
+	runtime.HandleError(fmt.Errorf("dropping product %s out of the queue, %s", key, message))
+
+	namespace, name, splitErr := cache.SplitMetaNamespaceKey(key)
+	if splitErr != nil {
+		return
 	}
 
-	if obj != nil {
-		pdt = obj.(*pdtv1.Product)
+	pdt, getErr := c.pdtLister.Products(namespace).Get(name)
+	if getErr != nil {
+		return
+	}
+
+	isDeleting := !pdt.ObjectMeta.DeletionTimestamp.IsZero()
+	ctx := withRetries(context.Background(), c.pdtQueue.NumRequeues(key))
+
+	if _, updateErr := writeStatus(ctx, pdt, c.clients, func(status *pdtv1.ProductStatus) {
+		setCondition(status, ConditionTypeFailed, pdtv1.ConditionTrue, reason, message)
+		setCondition(status, ConditionTypeReady, pdtv1.ConditionFalse, reason, message)
+
+		if !isDeleting {
+			status.CurrentStatus.Phase = pdtv1.ProductFailed
+		}
+	}, lastOperation(ctx, pdt, pdtv1.ProductStateFailed, reason, message)); updateErr != nil {
+		logger.SetStepState(lc.Error).Errorf("recording failure on product %s status failed: %v", key, updateErr)
 	}
 
-	return c.processItem(pdt, c.clients, c.recorder)
+	c.recorder.Event(pdt, corev1.EventTypeWarning, reason, message)
 }
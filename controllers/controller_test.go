@@ -1,20 +1,31 @@
 package controllers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/arutselvan15/estore-common/clients"
 	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
 	pdtInformers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions"
 	v1 "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions/estore/v1"
+	pdtv1Listers "github.com/arutselvan15/estore-product-kube-client/pkg/client/listers/estore/v1"
 
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
 	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
 )
 
 const (
@@ -26,6 +37,7 @@ func TestNewController(t *testing.T) {
 		pdtInformer v1.ProductInformer
 		pdtQueue    workqueue.RateLimitingInterface
 		clients     clients.EstoreClientInterface
+		backend     backend.ProductBackend
 		recorder    record.EventRecorder
 		processItem ProcessItemType
 	}
@@ -42,13 +54,13 @@ func TestNewController(t *testing.T) {
 		want bool
 	}{
 		{
-			name: "success new controller", args: args{pdtInformer: pdtInformer, pdtQueue: pdtQueue, clients: fakeClients, recorder: recorder, processItem: ProcessItem}, want: true,
+			name: "success new controller", args: args{pdtInformer: pdtInformer, pdtQueue: pdtQueue, clients: fakeClients, backend: backend.NewMemoryBackend(), recorder: recorder, processItem: ProcessItem}, want: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewController(tt.args.pdtInformer, tt.args.pdtQueue, tt.args.clients, tt.args.recorder, tt.args.processItem); (got != nil) != tt.want {
+			if got := NewController([]v1.ProductInformer{tt.args.pdtInformer}, Scope{}, tt.args.pdtQueue, tt.args.clients, tt.args.backend, tt.args.recorder, tt.args.processItem); (got != nil) != tt.want {
 				t.Errorf("NewController() = %v, want %v", got, tt.want)
 			}
 		})
@@ -57,10 +69,11 @@ func TestNewController(t *testing.T) {
 
 func TestController_doSync(t *testing.T) {
 	type fields struct {
-		pdtInformer     cache.SharedIndexInformer
+		pdtLister       pdtv1Listers.ProductLister
 		pdtListerSynced cache.InformerSynced
 		pdtQueue        workqueue.RateLimitingInterface
 		clients         clients.EstoreClientInterface
+		backend         backend.ProductBackend
 		recorder        record.EventRecorder
 		processItem     ProcessItemType
 	}
@@ -87,24 +100,25 @@ func TestController_doSync(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name: "success do sync", args: args{key: fmt.Sprintf("%s/%s", pdt.Namespace, pdt.Name)}, fields: fields{pdtInformer: pdtInformer.Informer(), pdtQueue: pdtQueue, clients: fakeClients, recorder: recorder, processItem: ProcessItem}, wantErr: false,
+			name: "success do sync", args: args{key: fmt.Sprintf("%s/%s", pdt.Namespace, pdt.Name)}, fields: fields{pdtLister: pdtInformer.Lister(), pdtQueue: pdtQueue, clients: fakeClients, backend: backend.NewMemoryBackend(), recorder: recorder, processItem: ProcessItem}, wantErr: false,
 		},
 		{
-			name: "failure do sync key not found in store", args: args{key: "unknown-key"}, fields: fields{pdtInformer: pdtInformer.Informer(), pdtQueue: pdtQueue, clients: fakeClients, recorder: recorder, processItem: ProcessItem}, wantErr: false,
+			name: "failure do sync key not found in store", args: args{key: "unknown-key"}, fields: fields{pdtLister: pdtInformer.Lister(), pdtQueue: pdtQueue, clients: fakeClients, backend: backend.NewMemoryBackend(), recorder: recorder, processItem: ProcessItem}, wantErr: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Controller{
-				pdtInformer:     tt.fields.pdtInformer,
+				pdtLister:       tt.fields.pdtLister,
 				pdtListerSynced: tt.fields.pdtListerSynced,
 				pdtQueue:        tt.fields.pdtQueue,
 				clients:         tt.fields.clients,
+				backend:         tt.fields.backend,
 				recorder:        tt.fields.recorder,
 				processItem:     tt.fields.processItem,
 			}
-			if err := c.doSync(tt.args.key); (err != nil) != tt.wantErr {
+			if _, err := c.doSync(context.Background(), tt.args.key); (err != nil) != tt.wantErr {
 				t.Errorf("doSync() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -113,10 +127,11 @@ func TestController_doSync(t *testing.T) {
 
 func TestController_processNextItem(t *testing.T) {
 	type fields struct {
-		pdtInformer     cache.SharedIndexInformer
+		pdtLister       pdtv1Listers.ProductLister
 		pdtListerSynced cache.InformerSynced
 		pdtQueue        workqueue.RateLimitingInterface
 		clients         clients.EstoreClientInterface
+		backend         backend.ProductBackend
 		recorder        record.EventRecorder
 		processItem     ProcessItemType
 	}
@@ -139,23 +154,743 @@ func TestController_processNextItem(t *testing.T) {
 		want   bool
 	}{
 		{
-			name: "success process next item", fields: fields{pdtInformer: pdtInformer.Informer(), pdtQueue: pdtQueue, clients: fakeClients, recorder: recorder, processItem: ProcessItem}, want: true,
+			name: "success process next item", fields: fields{pdtLister: pdtInformer.Lister(), pdtQueue: pdtQueue, clients: fakeClients, backend: backend.NewMemoryBackend(), recorder: recorder, processItem: ProcessItem}, want: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Controller{
-				pdtInformer:     tt.fields.pdtInformer,
+				pdtLister:       tt.fields.pdtLister,
 				pdtListerSynced: tt.fields.pdtListerSynced,
 				pdtQueue:        tt.fields.pdtQueue,
 				clients:         tt.fields.clients,
+				backend:         tt.fields.backend,
 				recorder:        tt.fields.recorder,
 				processItem:     tt.fields.processItem,
 			}
-			if got := c.processNextItem(); got != tt.want {
+			if got := c.processNextItem(context.Background()); got != tt.want {
 				t.Errorf("processNextItem() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestController_processNextItem_maxRetries(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	key := pdt.Namespace + "/" + pdt.Name
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtInformerFactory := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration)
+	pdtInformer := pdtInformerFactory.Estore().V1().Products()
+	_ = pdtInformer.Informer().GetIndexer().Add(pdt)
+
+	// no retry delay, so every failed key is back in the queue right away
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(0, 0), "test")
+	defer pdtQueue.ShutDown()
+
+	recorder := record.NewFakeRecorder(fakeRecorderSize)
+
+	maxRetries := cfg.MaxRetries
+	cfg.MaxRetries = 2
+
+	defer func() { cfg.MaxRetries = maxRetries }()
+
+	c := &Controller{
+		pdtLister: pdtInformer.Lister(),
+		pdtQueue:  pdtQueue,
+		clients:   fakeClients,
+		backend:   backend.NewMemoryBackend(),
+		recorder:  recorder,
+		processItem: func(context.Context, *pdtv1.Product, clients.EstoreClientInterface, backend.ProductBackend, record.EventRecorder) (Result, error) {
+			return Result{}, errors.New("backend unavailable")
+		},
+	}
+
+	pdtQueue.Add(key)
+
+	// first attempt and two retries
+	for i := 0; i <= cfg.MaxRetries; i++ {
+		if err := wait.PollImmediate(time.Millisecond, 5*time.Second, func() (bool, error) {
+			return pdtQueue.Len() == 1, nil
+		}); err != nil {
+			t.Fatalf("attempt %d: key not in queue", i+1)
+		}
+
+		c.processNextItem(context.Background())
+	}
+
+	if pdtQueue.Len() != 0 || pdtQueue.NumRequeues(key) != 0 {
+		t.Errorf("processNextItem() queue length = %d, requeues = %d, want dropped", pdtQueue.Len(), pdtQueue.NumRequeues(key))
+	}
+
+	got, err := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace).Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if cond := getCondition(&got.Status, ConditionTypeFailed); cond == nil || cond.Status != pdtv1.ConditionTrue ||
+		cond.Reason != ReasonRetriesExhausted || got.Status.CurrentStatus.Phase != pdtv1.ProductFailed {
+		t.Errorf("processNextItem() failed condition = %+v, phase = %v", cond, got.Status.CurrentStatus.Phase)
+	}
+
+	if lastOp := got.Status.LastOperation; lastOp.State != pdtv1.ProductStateFailed ||
+		lastOp.Description != "RetriesExhausted, retries: 2, last error: giving up after 2 retries: backend unavailable" {
+		t.Errorf("processNextItem() last operation = %+v", lastOp)
+	}
+
+	if event := <-recorder.Events; event != "Warning RetriesExhausted giving up after 2 retries: backend unavailable" {
+		t.Errorf("processNextItem() event = %v", event)
+	}
+}
+
+func TestController_processNextItem_result(t *testing.T) {
+	pdt := makeTestProduct()
+	key := pdt.Namespace + "/" + pdt.Name
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtInformer := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration).Estore().V1().Products()
+	_ = pdtInformer.Informer().GetIndexer().Add(pdt)
+
+	tests := []struct {
+		name         string
+		result       Result
+		err          error
+		wantQueued   bool
+		wantDelayed  bool
+		wantRequeues int
+	}{
+		{name: "success done", result: Result{}, wantQueued: false, wantDelayed: false, wantRequeues: 0},
+		{name: "success requeue", result: Result{Requeue: true}, wantQueued: true, wantDelayed: false, wantRequeues: 0},
+		{name: "success requeue after", result: Result{RequeueAfter: 50 * time.Millisecond}, wantQueued: false, wantDelayed: true, wantRequeues: 0},
+		{name: "success requeue after wins over requeue", result: Result{Requeue: true, RequeueAfter: 50 * time.Millisecond}, wantQueued: false, wantDelayed: true, wantRequeues: 0},
+		{name: "failure error rate limited", result: Result{Requeue: true}, err: errors.New("backend unavailable"), wantQueued: false, wantDelayed: true, wantRequeues: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+			defer pdtQueue.ShutDown()
+
+			c := &Controller{
+				pdtLister: pdtInformer.Lister(),
+				pdtQueue:  pdtQueue,
+				clients:   fakeClients,
+				backend:   backend.NewMemoryBackend(),
+				recorder:  record.NewFakeRecorder(fakeRecorderSize),
+				processItem: func(context.Context, *pdtv1.Product, clients.EstoreClientInterface, backend.ProductBackend, record.EventRecorder) (Result, error) {
+					return tt.result, tt.err
+				},
+			}
+
+			pdtQueue.Add(key)
+			c.processNextItem(context.Background())
+
+			if got := pdtQueue.Len() == 1; got != tt.wantQueued {
+				t.Errorf("processNextItem() queued = %v, want %v", got, tt.wantQueued)
+			}
+
+			if got := pdtQueue.NumRequeues(key); got != tt.wantRequeues {
+				t.Errorf("processNextItem() requeues = %d, want %d", got, tt.wantRequeues)
+			}
+
+			if !tt.wantDelayed {
+				return
+			}
+
+			if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+				return pdtQueue.Len() == 1, nil
+			}); err != nil {
+				t.Errorf("processNextItem() key not requeued after the delay")
+			}
+		})
+	}
+}
+
+func TestController_RequeueNotReady(t *testing.T) {
+	ready := makeProduct("testNs", "ready", "testBrand", 100, []string{"test"}, pdtv1.ProductAvailable)
+	setCondition(&ready.Status, ConditionTypeReady, pdtv1.ConditionTrue, ReasonAvailable, "product available")
+
+	blocked := makeProduct("testNs", "blocked", "testBrand", 100, []string{"test"}, pdtv1.ProductFailed)
+	setCondition(&blocked.Status, ConditionTypeBlocked, pdtv1.ConditionTrue, ReasonBlacklistedNamespace, "namespace testNs is blacklisted")
+	setCondition(&blocked.Status, ConditionTypeReady, pdtv1.ConditionFalse, ReasonBlacklistedNamespace, "namespace testNs is blacklisted")
+
+	frozen := ready.DeepCopy()
+	frozen.Name = "frozen"
+	setCondition(&frozen.Status, ConditionTypeFrozen, pdtv1.ConditionTrue, ReasonFreezeWindow, freezeMessage)
+
+	pending := makeProduct("testNs", "pending", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	outOfScope := makeProduct("otherNs", "pending", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig(nil, nil)
+	pdtInformer := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration).Estore().V1().Products()
+
+	for _, pdt := range []*pdtv1.Product{ready, blocked, frozen, pending, outOfScope} {
+		_ = pdtInformer.Informer().GetIndexer().Add(pdt)
+	}
+
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+	defer pdtQueue.ShutDown()
+
+	c := &Controller{
+		pdtLister: newScopedLister([]v1.ProductInformer{pdtInformer}, nil),
+		pdtQueue:  pdtQueue,
+		scope:     Scope{Namespaces: []string{"testNs"}},
+	}
+
+	c.RequeueNotReady()
+
+	var keys []string
+	for pdtQueue.Len() > 0 {
+		key, _ := pdtQueue.Get()
+		keys = append(keys, key.(string))
+		pdtQueue.Done(key)
+	}
+
+	sort.Strings(keys)
+
+	if want := []string{"testNs/blocked", "testNs/frozen", "testNs/pending"}; fmt.Sprint(keys) != fmt.Sprint(want) {
+		t.Errorf("RequeueNotReady() queued = %v, want %v", keys, want)
+	}
+}
+
+func TestController_Ready(t *testing.T) {
+	fakeClients := fakecc.NewEstoreFakeClientForConfig(nil, nil)
+	pdtInformerFactory := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration)
+	pdtInformer := pdtInformerFactory.Estore().V1().Products()
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+	c := NewController([]v1.ProductInformer{pdtInformer}, Scope{}, pdtQueue, fakeClients, backend.NewMemoryBackend(), record.NewFakeRecorder(fakeRecorderSize), ProcessItem)
+
+	if err := c.Ready(); err != ErrNotReady {
+		t.Errorf("Ready() before run error = %v, want %v", err, ErrNotReady)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopCh := make(chan struct{})
+	runDone := make(chan struct{})
+
+	defer close(stopCh)
+
+	pdtInformerFactory.Start(stopCh)
+
+	go func() {
+		defer close(runDone)
+		c.Run(ctx, 1)
+	}()
+
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return c.Ready() == nil, nil
+	}); err != nil {
+		t.Errorf("Ready() after cache sync error = %v, want nil", c.Ready())
+	}
+
+	cancel()
+	<-runDone
+
+	if err := c.Ready(); err != ErrNotReady {
+		t.Errorf("Ready() after stop error = %v, want %v", err, ErrNotReady)
+	}
+}
+
+func TestController_SetWorkers(t *testing.T) {
+	fakeClients := fakecc.NewEstoreFakeClientForConfig(nil, nil)
+	pdtInformerFactory := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration)
+	pdtInformer := pdtInformerFactory.Estore().V1().Products()
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+	c := NewController([]v1.ProductInformer{pdtInformer}, Scope{}, pdtQueue, fakeClients, backend.NewMemoryBackend(), record.NewFakeRecorder(fakeRecorderSize), ProcessItem)
+
+	// not running yet
+	c.SetWorkers(3)
+
+	if got := c.Workers(); got != 0 {
+		t.Errorf("Workers() before run = %d, want 0", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopCh := make(chan struct{})
+	runDone := make(chan struct{})
+
+	defer close(stopCh)
+
+	pdtInformerFactory.Start(stopCh)
+
+	go func() {
+		defer close(runDone)
+		c.Run(ctx, 1)
+	}()
+
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return c.Workers() == 1, nil
+	}); err != nil {
+		t.Fatalf("Workers() after run = %d, want 1", c.Workers())
+	}
+
+	for _, want := range []int{4, 2, 0, 1} {
+		c.SetWorkers(want)
+
+		if got := c.Workers(); got != want {
+			t.Errorf("SetWorkers(%d) workers = %d", want, got)
+		}
+	}
+
+	cancel()
+	<-runDone
+
+	if got := c.Workers(); got != 0 {
+		t.Errorf("Workers() after stop = %d, want 0", got)
+	}
+}
+
+func TestController_Healthy(t *testing.T) {
+	tests := []struct {
+		name     string
+		running  int32
+		lastPull time.Time
+		queued   bool
+		wantErr  bool
+	}{
+		{name: "success workers not running", running: 0, lastPull: time.Now().Add(-time.Hour), queued: true, wantErr: false},
+		{name: "success queue empty", running: 1, lastPull: time.Now().Add(-time.Hour), queued: false, wantErr: false},
+		{name: "success recent pull", running: 1, lastPull: time.Now(), queued: true, wantErr: false},
+		{name: "failure workers stalled", running: 1, lastPull: time.Now().Add(-time.Hour), queued: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{
+				pdtQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+				running:  tt.running,
+				lastPull: tt.lastPull.UnixNano(),
+			}
+			if tt.queued {
+				c.pdtQueue.Add("testNs/testPdt")
+			}
+
+			if err := c.Healthy(time.Minute); (err != nil) != tt.wantErr {
+				t.Errorf("Healthy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestController_doSync_notInCache(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductUnknown)
+	key := fmt.Sprintf("%s/%s", pdt.Namespace, pdt.Name)
+	emptyLister := pdtv1Listers.NewProductLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))
+
+	tests := []struct {
+		name        string
+		pdtObjects  []runtime.Object
+		tombstone   bool
+		inBackend   bool
+		frozen      bool
+		wantBackend bool
+	}{
+		{name: "success live get fallback processes product", pdtObjects: []runtime.Object{pdt}, wantBackend: true},
+		{name: "success gone product removed from backend", inBackend: true, wantBackend: false},
+		{name: "success gone product with tombstone removed from backend", tombstone: true, inBackend: true, wantBackend: false},
+		{name: "success gone product kept in backend during freeze", tombstone: true, inBackend: true, frozen: true, wantBackend: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdtBackend := backend.NewMemoryBackend()
+			if tt.inBackend {
+				_ = pdtBackend.Upsert(context.TODO(), pdt)
+			}
+
+			c := &Controller{
+				pdtLister:   emptyLister,
+				pdtQueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+				clients:     fakecc.NewEstoreFakeClientForConfig(tt.pdtObjects, nil),
+				backend:     pdtBackend,
+				recorder:    record.NewFakeRecorder(fakeRecorderSize),
+				processItem: ProcessItem,
+			}
+
+			if tt.tombstone {
+				c.handleDelete(cache.DeletedFinalStateUnknown{Key: key, Obj: pdt})
+
+				if c.pdtQueue.Len() != 1 {
+					t.Errorf("handleDelete() queue length = %v, want 1", c.pdtQueue.Len())
+				}
+			}
+
+			if tt.frozen {
+				setFreeze(t, -time.Hour, time.Hour)
+
+				allowDeletes := cfg.FreezeAllowDeletes
+				cfg.FreezeAllowDeletes = false
+
+				defer func() { cfg.FreezeAllowDeletes = allowDeletes }()
+			}
+
+			result, err := c.doSync(context.Background(), key)
+			if err != nil {
+				t.Fatalf("doSync() error = %v", err)
+			}
+
+			if (result.RequeueAfter > 0) != tt.frozen {
+				t.Errorf("doSync() result = %+v, frozen %v", result, tt.frozen)
+			}
+
+			if _, ok := pdtBackend.Get(key); ok != tt.wantBackend {
+				t.Errorf("doSync() product in backend = %v, want %v", ok, tt.wantBackend)
+			}
+
+			if _, ok := c.deleted.Load(key); ok != (tt.tombstone && tt.frozen) {
+				t.Errorf("doSync() final state of %s kept = %v, want %v", key, ok, tt.tombstone && tt.frozen)
+			}
+		})
+	}
+}
+
+// errorBackend backend failing every call with err
+type errorBackend struct{ err error }
+
+func (b errorBackend) Upsert(ctx context.Context, pdt *pdtv1.Product) error { return b.err }
+
+func (b errorBackend) Delete(ctx context.Context, pdt *pdtv1.Product) error { return b.err }
+
+func TestController_processNextItem_deletedForgotten(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductAvailable)
+	key := pdt.Namespace + "/" + pdt.Name
+
+	tests := []struct {
+		name     string
+		backend  backend.ProductBackend
+		retries  int
+		wantKept bool
+	}{
+		{name: "success cleaned up", backend: backend.NewMemoryBackend(), wantKept: false},
+		{name: "success kept for retry", backend: failingBackend{}, wantKept: true},
+		{name: "failure permanent error", backend: errorBackend{err: NewPermanentError(errors.New("bad product"))}, wantKept: false},
+		{name: "failure retries exhausted", backend: failingBackend{}, retries: cfg.MaxRetries, wantKept: false},
+		{name: "failure cancelled on shutdown", backend: errorBackend{err: context.Canceled}, retries: cfg.MaxRetries, wantKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Hour, time.Hour), "test")
+			defer pdtQueue.ShutDown()
+
+			c := &Controller{
+				pdtLister:   pdtv1Listers.NewProductLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+				pdtQueue:    pdtQueue,
+				clients:     fakecc.NewEstoreFakeClientForConfig(nil, nil),
+				backend:     tt.backend,
+				recorder:    record.NewFakeRecorder(fakeRecorderSize),
+				processItem: ProcessItem,
+			}
+
+			for i := 0; i < tt.retries; i++ {
+				pdtQueue.AddRateLimited(key)
+			}
+
+			c.handleDelete(pdt)
+			c.processNextItem(context.Background())
+
+			if _, ok := c.deleted.Load(key); ok != tt.wantKept {
+				t.Errorf("processNextItem() final state kept = %v, want %v", ok, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestController_handleDelete(t *testing.T) {
+	pdt := makeTestProduct()
+
+	tests := []struct {
+		name string
+		obj  interface{}
+		want int
+	}{
+		{name: "success delete product", obj: pdt, want: 1},
+		{name: "success delete tombstone", obj: cache.DeletedFinalStateUnknown{Key: "testNs/testPdt", Obj: pdt}, want: 1},
+		{name: "failure delete unexpected object", obj: "unexpected", want: 0},
+		{name: "failure delete unexpected tombstone object", obj: cache.DeletedFinalStateUnknown{Key: "testNs/testPdt", Obj: "unexpected"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{
+				pdtQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+				recorder: record.NewFakeRecorder(fakeRecorderSize),
+			}
+
+			c.handleDelete(tt.obj)
+
+			if got := c.pdtQueue.Len(); got != tt.want {
+				t.Errorf("handleDelete() queue length = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_Run_drain(t *testing.T) {
+	gracePeriod := cfg.ShutdownGracePeriod
+	defer func() { cfg.ShutdownGracePeriod = gracePeriod }()
+
+	cfg.ShutdownGracePeriod = 200 * time.Millisecond
+
+	tests := []struct {
+		name          string
+		reconcileTime time.Duration
+		wantFinished  bool
+	}{
+		{name: "success in-flight reconcile drained", reconcileTime: 50 * time.Millisecond, wantFinished: true},
+		{name: "success in-flight reconcile abandoned after grace period", reconcileTime: time.Hour, wantFinished: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{
+				makeProduct("testNs", "testPdt1", "testBrand", 100, []string{"test"}, pdtv1.ProductPending),
+				makeProduct("testNs", "testPdt2", "testBrand", 100, []string{"test"}, pdtv1.ProductPending),
+			}, nil)
+			pdtInformerFactory := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration)
+			pdtInformer := pdtInformerFactory.Estore().V1().Products()
+			pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+
+			started := make(chan string, 2)
+			finished := make(chan string, 2)
+			cancelled := make(chan string, 2)
+
+			c := NewController([]v1.ProductInformer{pdtInformer}, Scope{}, pdtQueue, fakeClients, backend.NewMemoryBackend(),
+				record.NewFakeRecorder(fakeRecorderSize),
+				func(ctx context.Context, pdt *pdtv1.Product, _ clients.EstoreClientInterface, _ backend.ProductBackend, _ record.EventRecorder) (Result, error) {
+					started <- pdt.Name
+
+					select {
+					case <-time.After(tt.reconcileTime):
+						finished <- pdt.Name
+						return Result{}, nil
+					case <-ctx.Done():
+						cancelled <- pdt.Name
+						return Result{}, ctx.Err()
+					}
+				})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			stopCh := make(chan struct{})
+			runDone := make(chan struct{})
+
+			defer close(stopCh)
+
+			pdtInformerFactory.Start(stopCh)
+
+			go func() {
+				defer close(runDone)
+				c.Run(ctx, 1)
+			}()
+
+			// shut down while the only worker reconciles the first product
+			pdtName := <-started
+			cancel()
+
+			select {
+			case <-runDone:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Run() did not return after the grace period")
+			}
+
+			if got := len(finished) == 1; got != tt.wantFinished {
+				t.Errorf("Run() in-flight reconcile finished = %v, want %v", got, tt.wantFinished)
+			}
+
+			// an abandoned reconcile is cancelled
+			if !tt.wantFinished {
+				select {
+				case got := <-cancelled:
+					if got != pdtName {
+						t.Errorf("Run() cancelled reconcile of %s, want %s", got, pdtName)
+					}
+				case <-time.After(5 * time.Second):
+					t.Errorf("Run() abandoned reconcile of %s not cancelled", pdtName)
+				}
+			}
+
+			// no new key is taken once the shutdown started
+			if len(started) != 0 {
+				t.Errorf("Run() started %d reconciles after shutdown, want 0", len(started))
+			}
+		})
+	}
+}
+
+func TestController_processNextItem_timeout(t *testing.T) {
+	reconcileTimeout := cfg.ReconcileTimeout
+	defer func() { cfg.ReconcileTimeout = reconcileTimeout }()
+
+	cfg.ReconcileTimeout = 50 * time.Millisecond
+
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	pdt.Finalizers = []string{cfg.ProductOperatorFinalizer}
+	key := pdt.Namespace + "/" + pdt.Name
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtInformer := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration).Estore().V1().Products()
+	_ = pdtInformer.Informer().GetIndexer().Add(pdt)
+
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Hour, time.Hour), "test")
+	defer pdtQueue.ShutDown()
+
+	recorder := record.NewFakeRecorder(fakeRecorderSize)
+	c := &Controller{
+		pdtLister:   pdtInformer.Lister(),
+		pdtQueue:    pdtQueue,
+		clients:     fakeClients,
+		backend:     hangingBackend{},
+		recorder:    recorder,
+		processItem: ProcessItem,
+	}
+
+	pdtQueue.Add(key)
+
+	start := time.Now()
+	c.processNextItem(context.Background())
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("processNextItem() took %s, want about %s", elapsed, cfg.ReconcileTimeout)
+	}
+
+	// retried with backoff like any transient error
+	if got := pdtQueue.NumRequeues(key); got != 1 {
+		t.Errorf("processNextItem() requeues = %d, want 1", got)
+	}
+
+	got, err := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace).Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if cond := getCondition(&got.Status, ConditionTypeReady); cond == nil || cond.Reason != ReasonTimeout {
+		t.Errorf("processNextItem() ready condition = %+v, want reason %s", cond, ReasonTimeout)
+	}
+
+	if event := <-recorder.Events; event != "Warning Timeout context deadline exceeded" {
+		t.Errorf("processNextItem() event = %v", event)
+	}
+}
+
+func TestController_Run_concurrentWorkers(t *testing.T) {
+	const products = 12
+
+	objects := make([]runtime.Object, 0, products)
+	for i := 0; i < products; i++ {
+		objects = append(objects, makeProduct("testNs", fmt.Sprintf("testPdt%d", i), "testBrand", 100, []string{"test"}, pdtv1.ProductPending))
+	}
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig(objects, nil)
+	pdtInformerFactory := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration)
+	pdtInformer := pdtInformerFactory.Estore().V1().Products()
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+	pdtBackend := backend.NewMemoryBackend()
+
+	// logger fields each reconcile saw, keyed by product key
+	var seen sync.Map
+
+	c := NewController([]v1.ProductInformer{pdtInformer}, Scope{}, pdtQueue, fakeClients, pdtBackend, &record.FakeRecorder{},
+		func(ctx context.Context, pdt *pdtv1.Product, clients clients.EstoreClientInterface, pdtBackend backend.ProductBackend, recorder record.EventRecorder) (Result, error) {
+			data := gLog.FromContext(ctx).GetEntry().Data
+			seen.Store(pdt.Namespace+"/"+pdt.Name, fmt.Sprintf("%v %v", data["objectName"], data[gLog.CorrelationIDField]))
+
+			return ProcessItem(ctx, pdt, clients, pdtBackend, recorder)
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopCh := make(chan struct{})
+	runDone := make(chan struct{})
+
+	defer func() {
+		cancel()
+		<-runDone
+		close(stopCh)
+	}()
+
+	pdtInformerFactory.Start(stopCh)
+
+	go func() {
+		defer close(runDone)
+		c.Run(ctx, 4)
+	}()
+
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		for i := 0; i < products; i++ {
+			if _, ok := pdtBackend.Get(fmt.Sprintf("testNs/testPdt%d", i)); !ok {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("Run() did not sync all %d products: %v", products, err)
+	}
+
+	for i := 0; i < products; i++ {
+		key := fmt.Sprintf("testNs/testPdt%d", i)
+
+		got, ok := seen.Load(key)
+		if !ok {
+			t.Errorf("Run() product %s not reconciled", key)
+			continue
+		}
+
+		// every reconcile logs its own key and a correlation id
+		var objectName, id string
+		if _, scanErr := fmt.Sscan(got.(string), &objectName, &id); scanErr != nil || objectName != key || len(id) != 16 {
+			t.Errorf("Run() reconcile of %s logged with objectName and correlation id %q", key, got)
+		}
+	}
+}
+
+func TestController_processNextItem_correlationID(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	key := pdt.Namespace + "/" + pdt.Name
+
+	tests := []struct {
+		name    string
+		errs    []error
+		wantIDs []string
+		wantEnd bool
+	}{
+		{name: "success id of the event logged until the reconcile succeeds", errs: []error{nil}, wantIDs: []string{"event1"}, wantEnd: true},
+		{name: "success id of the event kept across retries", errs: []error{errors.New("backend unavailable"), nil}, wantIDs: []string{"event1", "event1"}, wantEnd: true},
+		{name: "success id kept while the reconcile fails", errs: []error{errors.New("backend unavailable")}, wantIDs: []string{"event1"}, wantEnd: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+			pdtInformer := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration).Estore().V1().Products()
+			_ = pdtInformer.Informer().GetIndexer().Add(pdt)
+
+			var gotIDs []string
+
+			c := &Controller{
+				pdtLister: pdtInformer.Lister(),
+				pdtQueue:  workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(0, 0), "test"),
+				clients:   fakeClients,
+				recorder:  record.NewFakeRecorder(fakeRecorderSize),
+				processItem: func(ctx context.Context, _ *pdtv1.Product, _ clients.EstoreClientInterface, _ backend.ProductBackend, _ record.EventRecorder) (Result, error) {
+					gotIDs = append(gotIDs, fmt.Sprint(gLog.FromContext(ctx).GetEntry().Data[gLog.CorrelationIDField]))
+					return Result{}, tt.errs[len(gotIDs)-1]
+				},
+			}
+			defer c.pdtQueue.ShutDown()
+
+			c.enqueue(key, "event1", gLog.NewLogger())
+
+			for range tt.errs {
+				c.processNextItem(context.Background())
+			}
+
+			if fmt.Sprint(gotIDs) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("processNextItem() correlation ids = %v, want %v", gotIDs, tt.wantIDs)
+			}
+
+			if _, pending := c.correlations.Load(key); pending == tt.wantEnd {
+				t.Errorf("processNextItem() correlation id pending = %v, want %v", pending, !tt.wantEnd)
+			}
+		})
+	}
+}
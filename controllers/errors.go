@@ -0,0 +1,96 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+)
+
+// ErrorKind decides how a failed reconcile is retried
+type ErrorKind int
+
+const (
+	// ErrorKindTransient retried with the rate limiter backoff
+	ErrorKindTransient ErrorKind = iota
+	// ErrorKindConflict retried with backoff and a fresh read of the product, counts against cfg.MaxRetries
+	ErrorKindConflict
+	// ErrorKindPermanent not retried, the product is marked failed
+	ErrorKindPermanent
+	// ErrorKindTimeout transient error of a reconcile or call exceeding its timeout, retried with backoff
+	ErrorKindTimeout
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindConflict:
+		return "conflict"
+	case ErrorKindPermanent:
+		return "permanent"
+	case ErrorKindTimeout:
+		return "timeout"
+	default:
+		return "transient"
+	}
+}
+
+// ReconcileError error of the given kind, errors not wrapped in a ReconcileError are classified by errorKind
+type ReconcileError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *ReconcileError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap the wrapped error
+func (e *ReconcileError) Unwrap() error {
+	return e.Err
+}
+
+// NewTransientError transient error retried with backoff
+func NewTransientError(err error) error {
+	return &ReconcileError{Kind: ErrorKindTransient, Err: err}
+}
+
+// NewConflictError conflict error requeued with a fresh read
+func NewConflictError(err error) error {
+	return &ReconcileError{Kind: ErrorKindConflict, Err: err}
+}
+
+// NewPermanentError permanent error that is not retried
+func NewPermanentError(err error) error {
+	return &ReconcileError{Kind: ErrorKindPermanent, Err: err}
+}
+
+// errorKind kind of the error, api conflicts are conflicts, exceeded deadlines are timeouts, api errors that do
+// not change on retry and catalog client errors are permanent, everything else is transient
+func errorKind(err error) ErrorKind {
+	var reconcileErr *ReconcileError
+	if errors.As(err, &reconcileErr) {
+		return reconcileErr.Kind
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return ErrorKindTimeout
+	case apierrors.IsConflict(err):
+		return ErrorKindConflict
+	case apierrors.IsNotFound(err), apierrors.IsGone(err), apierrors.IsInvalid(err), apierrors.IsBadRequest(err),
+		apierrors.IsMethodNotSupported(err):
+		return ErrorKindPermanent
+	}
+
+	var statusErr *backend.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code >= 400 && statusErr.Code < 500 &&
+		statusErr.Code != http.StatusRequestTimeout && statusErr.Code != http.StatusTooManyRequests {
+		return ErrorKindPermanent
+	}
+
+	return ErrorKindTransient
+}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/arutselvan15/estore-common/clients"
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	pdtInformers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+func Test_errorKind(t *testing.T) {
+	resource := pdtv1.Resource("product")
+	kind := pdtv1.SchemeGroupVersion.WithKind("Product").GroupKind()
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{name: "success plain error", err: errors.New("connection refused"), want: ErrorKindTransient},
+		{name: "success wrapped permanent error", err: fmt.Errorf("sync: %w", NewPermanentError(errors.New("bad spec"))), want: ErrorKindPermanent},
+		{name: "success explicit transient api error", err: NewTransientError(apierrors.NewNotFound(resource, "testPdt")), want: ErrorKindTransient},
+		{name: "success api conflict", err: apierrors.NewConflict(resource, "testPdt", errors.New("modified")), want: ErrorKindConflict},
+		{name: "success api not found", err: apierrors.NewNotFound(resource, "testPdt"), want: ErrorKindPermanent},
+		{name: "success api invalid", err: apierrors.NewInvalid(kind, "testPdt", field.ErrorList{field.Required(field.NewPath("spec"), "")}), want: ErrorKindPermanent},
+		{name: "success api server timeout", err: apierrors.NewServerTimeout(resource, "update", 1), want: ErrorKindTimeout},
+		{name: "success deadline exceeded", err: fmt.Errorf("catalog call: %w", context.DeadlineExceeded), want: ErrorKindTimeout},
+		{name: "success cancelled", err: context.Canceled, want: ErrorKindTransient},
+		{name: "success catalog client error", err: &backend.StatusError{Code: http.StatusUnprocessableEntity}, want: ErrorKindPermanent},
+		{name: "success catalog throttled", err: &backend.StatusError{Code: http.StatusTooManyRequests}, want: ErrorKindTransient},
+		{name: "success catalog server error", err: &backend.StatusError{Code: http.StatusBadGateway}, want: ErrorKindTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorKind(tt.err); got != tt.want {
+				t.Errorf("errorKind() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_errorKind_httpBackendTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the catalog never replies
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := backend.NewHTTPBackend(server.URL, server.Client()).Upsert(ctx, makeTestProduct())
+	if got := errorKind(err); got != ErrorKindTimeout {
+		t.Errorf("errorKind() = %v, want %v, error = %v", got, ErrorKindTimeout, err)
+	}
+}
+
+func TestController_processNextItem_errorKinds(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	key := pdt.Namespace + "/" + pdt.Name
+
+	tests := []struct {
+		name          string
+		err           error
+		retries       int
+		wantQueued    bool
+		wantRequeues  int
+		wantFreshRead bool
+		wantFailed    string
+	}{
+		{name: "success transient error retried with backoff", err: errors.New("backend unavailable"), wantRequeues: 1},
+		{name: "success conflict retried with backoff and fresh read", err: apierrors.NewConflict(pdtv1.Resource("product"), pdt.Name, errors.New("modified")),
+			wantRequeues: 1, wantFreshRead: true},
+		{name: "success conflict retries exhausted", err: apierrors.NewConflict(pdtv1.Resource("product"), pdt.Name, errors.New("modified")),
+			retries: cfg.MaxRetries, wantFailed: ReasonRetriesExhausted},
+		{name: "success permanent error dropped", err: NewPermanentError(errors.New("bad spec")), wantFailed: ReasonPermanentError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+			pdtInformer := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration).Estore().V1().Products()
+			_ = pdtInformer.Informer().GetIndexer().Add(pdt)
+
+			pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Hour, time.Hour), "test")
+			defer pdtQueue.ShutDown()
+
+			c := &Controller{
+				pdtLister: pdtInformer.Lister(),
+				pdtQueue:  pdtQueue,
+				clients:   fakeClients,
+				backend:   backend.NewMemoryBackend(),
+				recorder:  record.NewFakeRecorder(fakeRecorderSize),
+				processItem: func(context.Context, *pdtv1.Product, clients.EstoreClientInterface, backend.ProductBackend, record.EventRecorder) (Result, error) {
+					return Result{}, tt.err
+				},
+			}
+
+			for i := 0; i < tt.retries; i++ {
+				pdtQueue.AddRateLimited(key)
+			}
+
+			pdtQueue.Add(key)
+			c.processNextItem(context.Background())
+
+			if got := pdtQueue.Len() == 1; got != tt.wantQueued {
+				t.Errorf("processNextItem() queued = %v, want %v", got, tt.wantQueued)
+			}
+
+			if got := pdtQueue.NumRequeues(key); got != tt.wantRequeues {
+				t.Errorf("processNextItem() requeues = %d, want %d", got, tt.wantRequeues)
+			}
+
+			if _, got := c.freshRead.Load(key); got != tt.wantFreshRead {
+				t.Errorf("processNextItem() fresh read = %v, want %v", got, tt.wantFreshRead)
+			}
+
+			got, err := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace).Get(pdt.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if cond := getCondition(&got.Status, ConditionTypeFailed); (cond != nil && cond.Reason == tt.wantFailed) != (tt.wantFailed != "") {
+				t.Errorf("processNextItem() failed condition = %+v, wantFailed %q", cond, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestController_doSync_freshRead(t *testing.T) {
+	stale := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	stale.ResourceVersion = "1"
+	live := stale.DeepCopy()
+	live.ResourceVersion = "2"
+	key := stale.Namespace + "/" + stale.Name
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{live}, nil)
+	pdtInformer := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration).Estore().V1().Products()
+	_ = pdtInformer.Informer().GetIndexer().Add(stale)
+
+	var got []string
+
+	c := &Controller{
+		pdtLister: pdtInformer.Lister(),
+		clients:   fakeClients,
+		backend:   backend.NewMemoryBackend(),
+		recorder:  record.NewFakeRecorder(fakeRecorderSize),
+		processItem: func(_ context.Context, pdt *pdtv1.Product, _ clients.EstoreClientInterface, _ backend.ProductBackend, _ record.EventRecorder) (Result, error) {
+			got = append(got, pdt.ResourceVersion)
+			return Result{}, nil
+		},
+	}
+
+	c.freshRead.Store(key, true)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.doSync(context.Background(), key); err != nil {
+			t.Fatalf("doSync() error = %v", err)
+		}
+	}
+
+	// the fresh read applies to the next sync only
+	if len(got) != 2 || got[0] != "2" || got[1] != "1" {
+		t.Errorf("doSync() resource versions = %v, want [2 1]", got)
+	}
+}
@@ -8,11 +8,12 @@ import (
 
 	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
 	lc "github.com/arutselvan15/go-utils/logconstants"
+
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
 )
 
-func onAdd(pdt *pdtv1.Product, recorder record.EventRecorder) string {
-	// checks for status if it is already available then dont add its resync
-	if pdt.Status.CurrentStatus.Phase == pdtv1.ProductAvailable {
+func onAdd(pdt *pdtv1.Product, recorder record.EventRecorder, logger gLog.Logger) string {
+	if !admit(nil, pdt) {
 		return ""
 	}
 
@@ -22,14 +23,14 @@ func onAdd(pdt *pdtv1.Product, recorder record.EventRecorder) string {
 	}
 
 	recorder.Event(pdt, corev1.EventTypeNormal, "Event", "Create")
-	log.SetOperation(lc.Create).SetObjectName(pdt.Name).SetObjectState(lc.Received).SetStep("").SetStepState("").LogAuditObject(pdt)
+	logger.SetOperation(lc.Create).SetObjectName(pdt.Name).SetObjectState(lc.Received).SetStep("").SetStepState("").LogAuditObject(pdt)
 
 	return key
 }
 
-func onUpdate(oldPdt, pdt *pdtv1.Product, recorder record.EventRecorder) string {
-	// checks for status if it is already available then dont add its resync
-	if pdt.Status.CurrentStatus.Phase == pdtv1.ProductAvailable {
+func onUpdate(oldPdt, pdt *pdtv1.Product, recorder record.EventRecorder, logger gLog.Logger) string {
+	// status only updates, including our own status writes, and resyncs are not admitted
+	if !admit(oldPdt, pdt) {
 		return ""
 	}
 
@@ -39,19 +40,19 @@ func onUpdate(oldPdt, pdt *pdtv1.Product, recorder record.EventRecorder) string
 	}
 
 	recorder.Event(pdt, corev1.EventTypeNormal, "Event", "Update")
-	log.SetOperation(lc.Update).SetObjectName(pdt.Name).SetObjectState(lc.Received).SetStep("").SetStepState("").LogAuditObject(oldPdt, pdt)
+	logger.SetOperation(lc.Update).SetObjectName(pdt.Name).SetObjectState(lc.Received).SetStep("").SetStepState("").LogAuditObject(oldPdt, pdt)
 
 	return key
 }
 
-func onDelete(pdt *pdtv1.Product, recorder record.EventRecorder) string {
+func onDelete(pdt *pdtv1.Product, recorder record.EventRecorder, logger gLog.Logger) string {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(pdt)
 	if err != nil {
 		return ""
 	}
 
 	recorder.Event(pdt, corev1.EventTypeNormal, "Event", "Delete")
-	log.SetOperation(lc.Delete).SetObjectName(pdt.Name).SetObjectState(lc.Received).SetStep("").SetStepState("").LogAuditObject(pdt)
+	logger.SetOperation(lc.Delete).SetObjectName(pdt.Name).SetObjectState(lc.Received).SetStep("").SetStepState("").LogAuditObject(pdt)
 
 	return key
 }
@@ -1,11 +1,21 @@
 package controllers
 
 import (
+	"context"
 	"testing"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
 	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	pdtInformers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
 )
 
 func Test_onAdd(t *testing.T) {
@@ -25,7 +35,7 @@ func Test_onAdd(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := onAdd(tt.args.pdt, tt.args.recorder); got != tt.want {
+			if got := onAdd(tt.args.pdt, tt.args.recorder, gLog.NewLogger()); got != tt.want {
 				t.Errorf("onAdd() = %v, want %v", got, tt.want)
 			}
 		})
@@ -49,7 +59,7 @@ func Test_onDelete(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := onDelete(tt.args.pdt, tt.args.recorder); got != tt.want {
+			if got := onDelete(tt.args.pdt, tt.args.recorder, gLog.NewLogger()); got != tt.want {
 				t.Errorf("onDelete() = %v, want %v", got, tt.want)
 			}
 		})
@@ -63,20 +73,89 @@ func Test_onUpdate(t *testing.T) {
 		recorder record.EventRecorder
 	}
 
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductUnknown)
+	pdt.Generation = 1
+	pdtStatusOnly := pdt.DeepCopy()
+	pdtStatusOnly.Status.CurrentStatus.Phase = pdtv1.ProductPending
+	pdtSpecChange := pdt.DeepCopy()
+	pdtSpecChange.Generation = 2
+	pdtAvailable := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductAvailable)
+	pdtAvailable.Generation = 1
+	pdtUnknown := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductUnknown)
+	pdtUnknown.Generation = 2
+	pdtUnknownOld := pdtUnknown.DeepCopy()
+	pdtUnknownOld.Generation = 1
+
 	tests := []struct {
 		name string
 		args args
 		want string
 	}{
-		{name: "success onUpdate already processed", args: args{pdt: makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductAvailable), recorder: record.NewFakeRecorder(fakeRecorderSize)}, want: ""},
-		{name: "success onUpdate return key", args: args{pdt: makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductUnknown), recorder: record.NewFakeRecorder(fakeRecorderSize)}, want: "testNs/testPdt"},
+		{name: "success onUpdate status only", args: args{oldPdt: pdt, pdt: pdtStatusOnly, recorder: record.NewFakeRecorder(fakeRecorderSize)}, want: ""},
+		{name: "success onUpdate resync", args: args{oldPdt: pdt, pdt: pdt, recorder: record.NewFakeRecorder(fakeRecorderSize)}, want: ""},
+		{name: "success onUpdate spec change", args: args{oldPdt: pdt, pdt: pdtSpecChange, recorder: record.NewFakeRecorder(fakeRecorderSize)}, want: "testNs/testPdt"},
+		{name: "success onUpdate already processed", args: args{oldPdt: pdtAvailable, pdt: pdtAvailable, recorder: record.NewFakeRecorder(fakeRecorderSize)}, want: ""},
+		{name: "success onUpdate return key", args: args{oldPdt: pdtUnknownOld, pdt: pdtUnknown, recorder: record.NewFakeRecorder(fakeRecorderSize)}, want: "testNs/testPdt"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := onUpdate(tt.args.oldPdt, tt.args.pdt, tt.args.recorder); got != tt.want {
+			if got := onUpdate(tt.args.oldPdt, tt.args.pdt, tt.args.recorder, gLog.NewLogger()); got != tt.want {
 				t.Errorf("onUpdate() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+// TestController_deleteAvailable an available product being deleted is reconciled, its backend entry removed and
+// the finalizer released instead of leaving the product terminating
+func TestController_deleteAvailable(t *testing.T) {
+	pdt := makeTestProduct()
+	pdt.Generation = 1
+	pdt.Finalizers = []string{cfg.ProductOperatorFinalizer}
+	pdt.Annotations = map[string]string{cfg.ObservedGenerationAnnotation: "1"}
+
+	pdtDeleting := pdt.DeepCopy()
+	now := metav1.Now()
+	pdtDeleting.DeletionTimestamp = &now
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdtDeleting}, nil)
+	pdtInformer := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration).Estore().V1().Products()
+	_ = pdtInformer.Informer().GetIndexer().Add(pdtDeleting)
+
+	pdtBackend := backend.NewMemoryBackend()
+	_ = pdtBackend.Upsert(context.TODO(), pdt)
+
+	c := &Controller{
+		pdtLister:   pdtInformer.Lister(),
+		pdtQueue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+		clients:     fakeClients,
+		backend:     pdtBackend,
+		recorder:    record.NewFakeRecorder(fakeRecorderSize),
+		processItem: ProcessItem,
+	}
+
+	key := onUpdate(pdt, pdtDeleting, c.recorder, gLog.NewLogger())
+	if key != "testNs/testPdt" {
+		t.Fatalf("onUpdate() = %q, want the key of the deleted available product", key)
+	}
+
+	c.pdtQueue.Add(key)
+
+	if !c.processNextItem(context.Background()) {
+		t.Fatalf("processNextItem() = false, want true")
+	}
+
+	if _, ok := pdtBackend.Get(key); ok {
+		t.Errorf("processNextItem() product %s still in backend", key)
+	}
+
+	got, err := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace).Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if len(got.Finalizers) != 0 {
+		t.Errorf("processNextItem() finalizers = %v, want none so the product is not stuck terminating", got.Finalizers)
+	}
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	"github.com/arutselvan15/estore-common/helper"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+func Test_addFinalizer(t *testing.T) {
+	pdt := makeTestProduct()
+	pdtWithFinalizers := makeTestProduct()
+	pdtWithFinalizers.Finalizers = []string{"other.finalizer"}
+
+	tests := []struct {
+		name    string
+		pdt     *pdtv1.Product
+		want    []string
+		wantErr bool
+	}{
+		{name: "success add first finalizer", pdt: pdt, want: []string{cfg.ProductOperatorFinalizer}, wantErr: false},
+		{name: "success append finalizer", pdt: pdtWithFinalizers, want: []string{"other.finalizer", cfg.ProductOperatorFinalizer}, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := addFinalizer(context.Background(), tt.pdt, fakecc.NewEstoreFakeClientForConfig([]runtime.Object{tt.pdt}, nil))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addFinalizer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(got.Finalizers) != len(tt.want) || got.Finalizers[len(tt.want)-1] != cfg.ProductOperatorFinalizer {
+				t.Errorf("addFinalizer() finalizers = %v, want %v", got.Finalizers, tt.want)
+			}
+		})
+	}
+
+	// finalizers changed since they were read: the write conflicts and the finalizer is added to the fresh list
+	stale := pdtWithFinalizers.DeepCopy()
+	stale.Finalizers = []string{"stale.finalizer"}
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdtWithFinalizers}, nil)
+	injectConflicts(fakeClients, 1)
+
+	got, err := addFinalizer(context.Background(), stale, fakeClients)
+	if err != nil || len(got.Finalizers) != 2 || got.Finalizers[0] != "other.finalizer" {
+		t.Errorf("addFinalizer() stale finalizers = %v, error = %v, want [other.finalizer %s]", got, err, cfg.ProductOperatorFinalizer)
+	}
+}
+
+func Test_removeFinalizer(t *testing.T) {
+	pdt := makeTestProduct()
+	pdt.Finalizers = []string{"other.finalizer", cfg.ProductOperatorFinalizer}
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	injectConflicts(fakeClients, 1)
+
+	got, err := removeFinalizer(context.Background(), pdt, fakeClients)
+	if err != nil || len(got.Finalizers) != 1 || got.Finalizers[0] != "other.finalizer" {
+		t.Errorf("removeFinalizer() = %v, error = %v, want [other.finalizer]", got, err)
+	}
+}
+
+func TestProcessItem_finalizerLifecycle(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductUnknown)
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtClient := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace)
+	pdtBackend := backend.NewMemoryBackend()
+	key := pdt.Namespace + "/" + pdt.Name
+
+	// create: finalizer added and product synced
+	if _, err := ProcessItem(context.Background(), pdt, fakeClients, pdtBackend, record.NewFakeRecorder(fakeRecorderSize)); err != nil {
+		t.Fatalf("ProcessItem() create error = %v", err)
+	}
+
+	got, err := pdtClient.Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if !helper.ContainsString(got.Finalizers, cfg.ProductOperatorFinalizer) {
+		t.Errorf("ProcessItem() create finalizers = %v, want %v", got.Finalizers, cfg.ProductOperatorFinalizer)
+	}
+
+	if cond := getCondition(&got.Status, ConditionTypeFinalizer); cond == nil || cond.Status != pdtv1.ConditionTrue {
+		t.Errorf("ProcessItem() create finalizer condition = %+v, want true", cond)
+	}
+
+	if _, ok := pdtBackend.Get(key); !ok {
+		t.Errorf("ProcessItem() create backend missing %s", key)
+	}
+
+	// finalize: deletion requested, backend cleaned up and finalizer removed
+	now := metav1.Now()
+	got.DeletionTimestamp = &now
+
+	if got, err = pdtClient.Update(got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if _, err = ProcessItem(context.Background(), got, fakeClients, pdtBackend, record.NewFakeRecorder(fakeRecorderSize)); err != nil {
+		t.Fatalf("ProcessItem() finalize error = %v", err)
+	}
+
+	if got, err = pdtClient.Get(pdt.Name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if helper.ContainsString(got.Finalizers, cfg.ProductOperatorFinalizer) {
+		t.Errorf("ProcessItem() finalize finalizers = %v, want removed", got.Finalizers)
+	}
+
+	if _, ok := pdtBackend.Get(key); ok {
+		t.Errorf("ProcessItem() finalize backend still has %s", key)
+	}
+
+	// delete: nothing holds the product anymore
+	if err = pdtClient.Delete(pdt.Name, &metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err = pdtClient.Get(pdt.Name, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("Get() after delete error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+
+	cc "github.com/arutselvan15/estore-common/clients"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	lc "github.com/arutselvan15/go-utils/logconstants"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
+)
+
+// frozen returns the freeze message when the controller is inside the freeze window of the current runtime settings
+func frozen() (bool, string) {
+	freezeWindow := cfg.CurrentRuntime().Freeze
+
+	return freezeWindow.Enabled(cfg.Component, time.Now()), freezeWindow.Message
+}
+
+// freezeRequeueAfter duration until the freeze window closes, capped by cfg.FreezeRecheckInterval so a
+// reloaded window is picked up
+func freezeRequeueAfter() time.Duration {
+	requeueAfter := cfg.FreezeRecheckInterval
+
+	if endTime := cfg.CurrentRuntime().Freeze.End; !endTime.IsZero() {
+		if untilEnd := time.Until(endTime); untilEnd < requeueAfter {
+			requeueAfter = untilEnd
+		}
+	}
+
+	if requeueAfter < time.Second {
+		requeueAfter = time.Second
+	}
+
+	return requeueAfter
+}
+
+// freeze defers the reconcile of the product until the freeze window closes, the Frozen condition and the changes
+// are only written when they change the status
+func freeze(ctx context.Context, pdtCopy *pdtv1.Product, message string, clients cc.EstoreClientInterface, recorder record.EventRecorder,
+	changes ...statusChange) (Result, error) {
+	result := Result{RequeueAfter: freezeRequeueAfter()}
+
+	changes = append(changes, func(status *pdtv1.ProductStatus) {
+		setCondition(status, ConditionTypeFrozen, pdtv1.ConditionTrue, ReasonFreezeWindow, message)
+	}, lastOperation(ctx, pdtCopy, pdtv1.ProductStateProcessing, "deferred until the freeze window closes", ""))
+
+	if _, err := writeStatus(ctx, pdtCopy, clients, changes...); err != nil {
+		handleError(ctx, pdtCopy, err, recorder)
+		return Result{}, err
+	}
+
+	gLog.FromContext(ctx).SetObjectState(lc.Ignored).SetStepState(lc.Skip).Infof("process product %s deferred for %s, freeze: %s",
+		pdtCopy.Name, result.RequeueAfter, message)
+
+	return result, nil
+}
+
+// thaw marks a previously frozen product as no longer frozen
+func thaw(status *pdtv1.ProductStatus) {
+	if getCondition(status, ConditionTypeFrozen) != nil {
+		setCondition(status, ConditionTypeFrozen, pdtv1.ConditionFalse, ReasonFreezeOver, "freeze window closed")
+	}
+}
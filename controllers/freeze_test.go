@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/arutselvan15/estore-common/clients"
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	"github.com/arutselvan15/estore-common/helper"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+const freezeMessage = "code release in progress"
+
+// setFreeze sets a freeze window for the controller from start to end relative to now
+func setFreeze(t *testing.T, start, end time.Duration) {
+	setConfig(t, map[string]string{
+		"app.freeze.startTime":  time.Now().Add(start).Format(time.RFC3339),
+		"app.freeze.endTime":    time.Now().Add(end).Format(time.RFC3339),
+		"app.freeze.message":    freezeMessage,
+		"app.freeze.components": cfg.Component,
+		"app.system.namespaces": "kube",
+	})
+}
+
+func Test_freezeRequeueAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		end     time.Duration
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{name: "success window closes before recheck", end: time.Minute, wantMin: 58 * time.Second, wantMax: time.Minute},
+		{name: "success recheck before window closes", end: time.Hour, wantMin: cfg.FreezeRecheckInterval, wantMax: cfg.FreezeRecheckInterval},
+		{name: "success window closed", end: -time.Minute, wantMin: time.Second, wantMax: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFreeze(t, -time.Hour, tt.end)
+
+			if got := freezeRequeueAfter(); got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("freezeRequeueAfter() = %v, want between %v and %v", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestProcessItem_freeze(t *testing.T) {
+	now := metav1.Now()
+
+	tests := []struct {
+		name         string
+		namespace    string
+		start        time.Duration
+		deleting     bool
+		allowDeletes bool
+		wantFrozen   bool
+	}{
+		{name: "success frozen", namespace: "testNs", start: -time.Hour, wantFrozen: true},
+		{name: "success freeze not started", namespace: "testNs", start: time.Hour, wantFrozen: false},
+		{name: "success system namespace exempt", namespace: "kube-system", start: -time.Hour, wantFrozen: false},
+		{name: "success deletion let through", namespace: "testNs", start: -time.Hour, deleting: true, allowDeletes: true, wantFrozen: false},
+		{name: "success deletion frozen", namespace: "testNs", start: -time.Hour, deleting: true, allowDeletes: false, wantFrozen: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFreeze(t, tt.start, 2*time.Hour)
+
+			allowDeletes := cfg.FreezeAllowDeletes
+			cfg.FreezeAllowDeletes = tt.allowDeletes
+
+			defer func() { cfg.FreezeAllowDeletes = allowDeletes }()
+
+			pdt := makeProduct(tt.namespace, "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+			if tt.deleting {
+				pdt.DeletionTimestamp = &now
+				pdt.Finalizers = []string{cfg.ProductOperatorFinalizer}
+			}
+
+			fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+			pdtBackend := backend.NewMemoryBackend()
+
+			result, err := ProcessItem(context.Background(), pdt, fakeClients, pdtBackend, record.NewFakeRecorder(fakeRecorderSize))
+			if err != nil {
+				t.Fatalf("ProcessItem() error = %v, want nil", err)
+			}
+
+			if (result.RequeueAfter > 0) != tt.wantFrozen {
+				t.Fatalf("ProcessItem() result = %+v, wantFrozen %v", result, tt.wantFrozen)
+			}
+
+			got, err := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace).Get(pdt.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			cond := getCondition(&got.Status, ConditionTypeFrozen)
+			if tt.wantFrozen && (cond == nil || cond.Status != pdtv1.ConditionTrue || cond.Message != freezeMessage) {
+				t.Errorf("ProcessItem() frozen condition = %+v, want true with %q", cond, freezeMessage)
+			}
+
+			// a deferred deletion is still shown as deleting
+			if cond := getCondition(&got.Status, ConditionTypeDeleting); tt.deleting && tt.wantFrozen && (cond == nil || cond.Status != pdtv1.ConditionTrue) {
+				t.Errorf("ProcessItem() deleting condition = %+v, want true", cond)
+			}
+
+			// nothing changes while frozen
+			reconciled := helper.ContainsString(got.Finalizers, cfg.ProductOperatorFinalizer) != tt.deleting
+			if reconciled == tt.wantFrozen {
+				t.Errorf("ProcessItem() finalizers = %v, reconciled %v, wantFrozen %v", got.Finalizers, reconciled, tt.wantFrozen)
+			}
+		})
+	}
+}
+
+func TestProcessItem_freezeReload(t *testing.T) {
+	setFreeze(t, -time.Hour, time.Hour)
+
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtClient := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace)
+	pdtBackend := backend.NewMemoryBackend()
+
+	if result, err := ProcessItem(context.Background(), pdt, fakeClients, pdtBackend, record.NewFakeRecorder(fakeRecorderSize)); err != nil || result.RequeueAfter <= 0 {
+		t.Fatalf("ProcessItem() frozen result = %+v, error = %v, want requeue after", result, err)
+	}
+
+	// the window is closed early without a restart
+	setFreeze(t, -time.Hour, -time.Minute)
+
+	got, _ := pdtClient.Get(pdt.Name, metav1.GetOptions{})
+	if _, err := ProcessItem(context.Background(), got, fakeClients, pdtBackend, record.NewFakeRecorder(fakeRecorderSize)); err != nil {
+		t.Fatalf("ProcessItem() after freeze error = %v", err)
+	}
+
+	got, _ = pdtClient.Get(pdt.Name, metav1.GetOptions{})
+	if cond := getCondition(&got.Status, ConditionTypeFrozen); cond == nil || cond.Status != pdtv1.ConditionFalse {
+		t.Errorf("ProcessItem() after freeze frozen condition = %+v, want false", cond)
+	}
+
+	if pdtBackend.Len() != 1 {
+		t.Errorf("ProcessItem() after freeze backend = %d, want 1", pdtBackend.Len())
+	}
+}
+
+func TestController_processNextItem_frozen(t *testing.T) {
+	pdt := makeTestProduct()
+	key := pdt.Namespace + "/" + pdt.Name
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+
+	defer pdtQueue.ShutDown()
+
+	c := &Controller{
+		pdtLister: newScopedLister(nil, nil),
+		pdtQueue:  pdtQueue,
+		clients:   fakeClients,
+		backend:   backend.NewMemoryBackend(),
+		recorder:  record.NewFakeRecorder(fakeRecorderSize),
+		processItem: func(context.Context, *pdtv1.Product, clients.EstoreClientInterface, backend.ProductBackend, record.EventRecorder) (Result, error) {
+			return Result{RequeueAfter: 50 * time.Millisecond}, nil
+		},
+	}
+
+	pdtQueue.Add(key)
+
+	if !c.processNextItem(context.Background()) {
+		t.Fatalf("processNextItem() = false, want true")
+	}
+
+	if pdtQueue.Len() != 0 || pdtQueue.NumRequeues(key) != 0 {
+		t.Errorf("processNextItem() queue length = %d, requeues = %d, want 0, 0", pdtQueue.Len(), pdtQueue.NumRequeues(key))
+	}
+
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return pdtQueue.Len() == 1, nil
+	}); err != nil {
+		t.Errorf("processNextItem() key not requeued after freeze delay")
+	}
+}
@@ -0,0 +1,65 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+
+	cc "github.com/arutselvan15/estore-common/clients"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+// observedGeneration last generation reconciled successfully from its annotation, 0 when not recorded
+func observedGeneration(pdt *pdtv1.Product) int64 {
+	generation, err := strconv.ParseInt(pdt.Annotations[cfg.ObservedGenerationAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return generation
+}
+
+// isObserved true when the current generation was reconciled successfully and the product is still ready
+func isObserved(pdt *pdtv1.Product) bool {
+	cond := getCondition(&pdt.Status, ConditionTypeReady)
+
+	return pdt.Generation > 0 && observedGeneration(pdt) == pdt.Generation &&
+		cond != nil && cond.Status == pdtv1.ConditionTrue
+}
+
+// specChanged true when the update carries a spec change, status only updates and resyncs keep the generation
+func specChanged(oldPdt, pdt *pdtv1.Product) bool {
+	return oldPdt.Generation != pdt.Generation || !apiequality.Semantic.DeepEqual(oldPdt.Spec, pdt.Spec)
+}
+
+// recordObservedGeneration patches the observed generation annotation when it differs from the generation
+func recordObservedGeneration(ctx context.Context, pdtCopy *pdtv1.Product, clients cc.EstoreClientInterface) error {
+	if pdtCopy.Generation == 0 || observedGeneration(pdtCopy) == pdtCopy.Generation {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				cfg.ObservedGenerationAnnotation: strconv.FormatInt(pdtCopy.Generation, 10),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = clients.GetProductClient().EstoreV1().Products(pdtCopy.Namespace).Patch(pdtCopy.Name, types.MergePatchType, patch)
+
+	return err
+}
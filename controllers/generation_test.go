@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+// countingBackend memory backend counting upserts
+type countingBackend struct {
+	*backend.MemoryBackend
+	upserts int
+}
+
+func (b *countingBackend) Upsert(ctx context.Context, pdt *pdtv1.Product) error {
+	b.upserts++
+	return b.MemoryBackend.Upsert(ctx, pdt)
+}
+
+func Test_specChanged(t *testing.T) {
+	pdt := makeTestProduct()
+	pdt.Generation = 1
+
+	statusOnly := pdt.DeepCopy()
+	statusOnly.Status.CurrentStatus.Phase = pdtv1.ProductFailed
+
+	newGeneration := pdt.DeepCopy()
+	newGeneration.Generation = 2
+
+	newSpec := pdt.DeepCopy()
+	newSpec.Spec.Price = 200
+
+	tests := []struct {
+		name   string
+		oldPdt *pdtv1.Product
+		pdt    *pdtv1.Product
+		want   bool
+	}{
+		{name: "success resync", oldPdt: pdt, pdt: pdt, want: false},
+		{name: "success status only", oldPdt: pdt, pdt: statusOnly, want: false},
+		{name: "success new generation", oldPdt: pdt, pdt: newGeneration, want: true},
+		{name: "success spec change without generation", oldPdt: pdt, pdt: newSpec, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := specChanged(tt.oldPdt, tt.pdt); got != tt.want {
+				t.Errorf("specChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessItem_observedGeneration(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductUnknown)
+	pdt.Generation = 2
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtBackend := &countingBackend{MemoryBackend: backend.NewMemoryBackend()}
+
+	if _, err := ProcessItem(context.Background(), pdt, fakeClients, pdtBackend, record.NewFakeRecorder(fakeRecorderSize)); err != nil {
+		t.Fatalf("ProcessItem() error = %v", err)
+	}
+
+	got, err := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace).Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if got.Annotations[cfg.ObservedGenerationAnnotation] != "2" {
+		t.Errorf("ProcessItem() observed generation = %v, want 2", got.Annotations[cfg.ObservedGenerationAnnotation])
+	}
+
+	// reconciling the same generation again is a no-op
+	if _, err := ProcessItem(context.Background(), got, fakeClients, pdtBackend, record.NewFakeRecorder(fakeRecorderSize)); err != nil {
+		t.Fatalf("ProcessItem() error = %v", err)
+	}
+
+	if pdtBackend.upserts != 1 {
+		t.Errorf("ProcessItem() backend upserts = %v, want 1", pdtBackend.upserts)
+	}
+
+	// a new generation is reconciled
+	got.Generation = 3
+	if _, err := ProcessItem(context.Background(), got, fakeClients, pdtBackend, record.NewFakeRecorder(fakeRecorderSize)); err != nil {
+		t.Fatalf("ProcessItem() error = %v", err)
+	}
+
+	if pdtBackend.upserts != 2 {
+		t.Errorf("ProcessItem() backend upserts = %v, want 2", pdtBackend.upserts)
+	}
+}
@@ -0,0 +1,152 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	cc "github.com/arutselvan15/estore-common/clients"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+// ErrLeaderElectionLost returned when the lease is lost while the controller is still expected to run
+var ErrLeaderElectionLost = errors.New("leader election lost")
+
+// LeaderElectionConfig leader election config
+type LeaderElectionConfig struct {
+	Identity       string
+	LeaseName      string
+	LeaseNamespace string
+	LeaseDuration  time.Duration
+	RenewDeadline  time.Duration
+	RetryPeriod    time.Duration
+}
+
+// NewLeaderElectionConfig leader election config for the identity using the configured lease settings
+func NewLeaderElectionConfig(identity string) LeaderElectionConfig {
+	return LeaderElectionConfig{
+		Identity:       identity,
+		LeaseName:      cfg.LeaseName,
+		LeaseNamespace: cfg.LeaseNamespace,
+		LeaseDuration:  cfg.LeaseDuration,
+		RenewDeadline:  cfg.RenewDeadline,
+		RetryPeriod:    cfg.RetryPeriod,
+	}
+}
+
+// RunWithLeaderElection blocks until ctx is done or the lease is lost, calling run only while holding the lease.
+// run must return once its context is done. The lease is only released once run returned, so no other replica
+// reconciles next to the reconciles run drains. ErrLeaderElectionLost is returned when the lease is lost
+// before ctx is done.
+func RunWithLeaderElection(ctx context.Context, clients cc.EstoreClientInterface, lec LeaderElectionConfig,
+	run func(ctx context.Context)) error {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: lec.LeaseName, Namespace: lec.LeaseNamespace},
+		Client:     clients.GetKubeClient().CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: lec.Identity},
+	}
+
+	var (
+		// guards run from being started once the election loop is stopping
+		mu      sync.Mutex
+		started bool
+		stopped bool
+		runDone = make(chan struct{})
+	)
+
+	// stopRun marks the election loop stopping and waits for a started run to return
+	stopRun := func() {
+		mu.Lock()
+		stopped = true
+		wait := started
+		mu.Unlock()
+
+		if wait {
+			<-runDone
+		}
+	}
+
+	// the election outlives ctx until run returned, cancelling it releases the lease
+	electionCtx, cancelElection := context.WithCancel(context.Background())
+	defer cancelElection()
+
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   lec.LeaseDuration,
+		RenewDeadline:   lec.RenewDeadline,
+		RetryPeriod:     lec.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            lec.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				mu.Lock()
+				if stopped {
+					mu.Unlock()
+					return
+				}
+				started = true
+				mu.Unlock()
+
+				defer close(runDone)
+
+				// run stops on shutdown, or right away once the lease is lost
+				runCtx, cancelRun := context.WithCancel(ctx)
+				defer cancelRun()
+
+				go func() {
+					select {
+					case <-leaderCtx.Done():
+						cancelRun()
+					case <-runCtx.Done():
+					}
+				}()
+
+				log.Infof("%s started leading lease %s/%s", lec.Identity, lec.LeaseNamespace, lec.LeaseName)
+				run(runCtx)
+			},
+			OnStoppedLeading: func() {
+				log.Infof("%s stopped leading lease %s/%s", lec.Identity, lec.LeaseNamespace, lec.LeaseName)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != lec.Identity {
+					log.Infof("%s observed new leader %s", lec.Identity, identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	electionDone := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			// keep renewing the lease while run drains, then release it
+			stopRun()
+			cancelElection()
+		case <-electionDone:
+		}
+	}()
+
+	le.Run(electionCtx)
+	close(electionDone)
+
+	// wait for the workers to stop, the lease context is already cancelled at this point
+	stopRun()
+
+	// the election loop only returns before ctx is done when the lease could not be renewed
+	if ctx.Err() == nil {
+		return ErrLeaderElectionLost
+	}
+
+	return nil
+}
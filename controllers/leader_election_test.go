@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+)
+
+func makeTestLeaderElectionConfig(identity string) LeaderElectionConfig {
+	return LeaderElectionConfig{
+		Identity:       identity,
+		LeaseName:      "test-lease",
+		LeaseNamespace: "testNs",
+		LeaseDuration:  time.Second,
+		RenewDeadline:  500 * time.Millisecond,
+		RetryPeriod:    100 * time.Millisecond,
+	}
+}
+
+func TestRunWithLeaderElection_failover(t *testing.T) {
+	fakeClients := fakecc.NewEstoreFakeClientForConfig(nil, nil)
+
+	startRun := func(identity string, leading chan<- string) (context.CancelFunc, <-chan error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+
+		go func() {
+			errCh <- RunWithLeaderElection(ctx, fakeClients, makeTestLeaderElectionConfig(identity), func(runCtx context.Context) {
+				leading <- identity
+				<-runCtx.Done()
+			})
+		}()
+
+		return cancel, errCh
+	}
+
+	leading := make(chan string, 2)
+
+	cancelFirst, errFirst := startRun("first", leading)
+	defer cancelFirst()
+
+	select {
+	case identity := <-leading:
+		if identity != "first" {
+			t.Fatalf("RunWithLeaderElection() leader = %v, want first", identity)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunWithLeaderElection() first instance never started leading")
+	}
+
+	cancelSecond, errSecond := startRun("second", leading)
+	defer cancelSecond()
+
+	// second instance must not run while the first holds the lease
+	select {
+	case identity := <-leading:
+		t.Fatalf("RunWithLeaderElection() %v started leading while lease is held", identity)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// stopping the first instance releases the lease and the second takes over
+	cancelFirst()
+
+	select {
+	case err := <-errFirst:
+		if err != nil {
+			t.Errorf("RunWithLeaderElection() first error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunWithLeaderElection() first instance did not shut down")
+	}
+
+	select {
+	case identity := <-leading:
+		if identity != "second" {
+			t.Fatalf("RunWithLeaderElection() leader = %v, want second", identity)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunWithLeaderElection() second instance never took over")
+	}
+
+	cancelSecond()
+
+	if err := <-errSecond; err != nil {
+		t.Errorf("RunWithLeaderElection() second error = %v, want nil", err)
+	}
+}
+
+func TestRunWithLeaderElection_drainHoldsLease(t *testing.T) {
+	fakeClients := fakecc.NewEstoreFakeClientForConfig(nil, nil)
+	lec := makeTestLeaderElectionConfig("first")
+
+	leading := make(chan string, 2)
+	draining := make(chan struct{})
+	drained := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errFirst := make(chan error, 1)
+
+	go func() {
+		errFirst <- RunWithLeaderElection(ctx, fakeClients, lec, func(runCtx context.Context) {
+			leading <- "first"
+			<-runCtx.Done()
+			// the reconciles in flight drain after the run context is done
+			close(draining)
+			<-drained
+		})
+	}()
+
+	if identity := <-leading; identity != "first" {
+		t.Fatalf("RunWithLeaderElection() leader = %v, want first", identity)
+	}
+
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	errSecond := make(chan error, 1)
+
+	go func() {
+		errSecond <- RunWithLeaderElection(secondCtx, fakeClients, makeTestLeaderElectionConfig("second"), func(runCtx context.Context) {
+			leading <- "second"
+			<-runCtx.Done()
+		})
+	}()
+
+	cancel()
+	<-draining
+
+	// the drain outlasts the lease duration, the lease must stay with the first instance all along
+	select {
+	case identity := <-leading:
+		t.Fatalf("RunWithLeaderElection() %v started leading while the first instance drains", identity)
+	case <-time.After(2 * lec.LeaseDuration):
+	}
+
+	lease, err := fakeClients.GetKubeClient().CoordinationV1().Leases(lec.LeaseNamespace).Get(lec.LeaseName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() lease error = %v", err)
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != "first" {
+		t.Errorf("RunWithLeaderElection() lease holder during drain = %v, want first", lease.Spec.HolderIdentity)
+	}
+
+	close(drained)
+
+	if err := <-errFirst; err != nil {
+		t.Errorf("RunWithLeaderElection() first error = %v, want nil", err)
+	}
+
+	select {
+	case identity := <-leading:
+		if identity != "second" {
+			t.Fatalf("RunWithLeaderElection() leader = %v, want second", identity)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunWithLeaderElection() second instance never took over after the drain")
+	}
+
+	cancelSecond()
+
+	if err := <-errSecond; err != nil {
+		t.Errorf("RunWithLeaderElection() second error = %v, want nil", err)
+	}
+}
+
+func TestRunWithLeaderElection_invalidConfig(t *testing.T) {
+	lec := makeTestLeaderElectionConfig("first")
+	lec.RenewDeadline = lec.LeaseDuration
+
+	err := RunWithLeaderElection(context.Background(), fakecc.NewEstoreFakeClientForConfig(nil, nil), lec, func(runCtx context.Context) {})
+	if err == nil {
+		t.Errorf("RunWithLeaderElection() error = %v, wantErr true", err)
+	}
+}
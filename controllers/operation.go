@@ -0,0 +1,56 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+)
+
+// retriesKey context key of the retries of the key being reconciled
+type retriesKey struct{}
+
+// withRetries ctx carrying the number of times the key of the reconcile was retried after a failure
+func withRetries(ctx context.Context, retries int) context.Context {
+	return context.WithValue(ctx, retriesKey{}, retries)
+}
+
+// retriesFrom retries carried by ctx, 0 when there are none
+func retriesFrom(ctx context.Context) int {
+	retries, _ := ctx.Value(retriesKey{}).(int)
+
+	return retries
+}
+
+// operationType operation the reconcile performs on the product, a product not reconciled successfully yet is created
+func operationType(pdt *pdtv1.Product) pdtv1.ProductOperationType {
+	switch {
+	case !pdt.ObjectMeta.DeletionTimestamp.IsZero():
+		return pdtv1.ProductOperationDelete
+	case observedGeneration(pdt) == 0:
+		return pdtv1.ProductOperationCreate
+	default:
+		return pdtv1.ProductOperationUpdate
+	}
+}
+
+// lastOperation records the reconcile of the product as its last operation along with the retries carried by ctx.
+// LastOperation has no fields of its own for the retries and the last error, both are part of the description.
+func lastOperation(ctx context.Context, pdt *pdtv1.Product, state pdtv1.ProductState, description, lastError string) statusChange {
+	opType, retries := operationType(pdt), retriesFrom(ctx)
+
+	if retries > 0 {
+		description = fmt.Sprintf("%s, retries: %d", description, retries)
+	}
+
+	if lastError != "" {
+		description = fmt.Sprintf("%s, last error: %s", description, lastError)
+	}
+
+	return func(status *pdtv1.ProductStatus) {
+		status.LastOperation = pdtv1.LastOperation{Type: opType, State: state, Description: description, LastUpdateTime: metav1.Now()}
+	}
+}
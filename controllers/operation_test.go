@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+func TestProcessItem_lastOperation(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+
+	pdtUpdate := pdt.DeepCopy()
+	pdtUpdate.Generation = 2
+	pdtUpdate.Annotations = map[string]string{cfg.ObservedGenerationAnnotation: "1"}
+
+	pdtDelete := pdt.DeepCopy()
+	now := metav1.Now()
+	pdtDelete.DeletionTimestamp = &now
+	pdtDelete.Finalizers = []string{cfg.ProductOperatorFinalizer}
+
+	tests := []struct {
+		name    string
+		pdt     *pdtv1.Product
+		backend backend.ProductBackend
+		retries int
+		want    pdtv1.LastOperation
+	}{
+		{
+			name: "success create", pdt: pdt, backend: backend.NewMemoryBackend(),
+			want: pdtv1.LastOperation{Type: pdtv1.ProductOperationCreate, State: pdtv1.ProductStateSuccessful, Description: "product synced to backend"},
+		},
+		{
+			name: "success update after retries", pdt: pdtUpdate, backend: backend.NewMemoryBackend(), retries: 2,
+			want: pdtv1.LastOperation{Type: pdtv1.ProductOperationUpdate, State: pdtv1.ProductStateSuccessful, Description: "product synced to backend, retries: 2"},
+		},
+		{
+			name: "failure create backend unavailable", pdt: pdt, backend: failingBackend{},
+			want: pdtv1.LastOperation{Type: pdtv1.ProductOperationCreate, State: pdtv1.ProductStateFailed, Description: "SyncFailed, last error: backend unavailable"},
+		},
+		{
+			name: "failure delete backend unavailable after retries", pdt: pdtDelete, backend: failingBackend{}, retries: 3,
+			want: pdtv1.LastOperation{Type: pdtv1.ProductOperationDelete, State: pdtv1.ProductStateFailed, Description: "DeleteFailed, retries: 3, last error: backend unavailable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{tt.pdt}, nil)
+			_, _ = ProcessItem(withRetries(context.Background(), tt.retries), tt.pdt, fakeClients, tt.backend, record.NewFakeRecorder(fakeRecorderSize))
+
+			got, err := fakeClients.GetProductClient().EstoreV1().Products(tt.pdt.Namespace).Get(tt.pdt.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			lastOp := got.Status.LastOperation
+			if lastOp.LastUpdateTime.IsZero() {
+				t.Errorf("ProcessItem() last operation update time not set")
+			}
+
+			lastOp.LastUpdateTime = metav1.Time{}
+			if lastOp != tt.want {
+				t.Errorf("ProcessItem() last operation = %+v, want %+v", lastOp, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	cc "github.com/arutselvan15/estore-common/clients"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	lc "github.com/arutselvan15/go-utils/logconstants"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
+)
+
+// requester user who created the product, from the requester annotation or else the first field manager
+func requester(pdt *pdtv1.Product) string {
+	if user := pdt.Annotations[pdtv1.ProductAnnotationRequester]; user != "" {
+		return user
+	}
+
+	if len(pdt.ManagedFields) > 0 {
+		return pdt.ManagedFields[0].Manager
+	}
+
+	return ""
+}
+
+// blockedBy reason and message when the product namespace or requester is blacklisted in the current runtime
+// settings, empty reason otherwise
+func blockedBy(pdt *pdtv1.Product) (string, string) {
+	rt := cfg.CurrentRuntime()
+
+	if cfg.Matches(pdt.Namespace, rt.BlacklistNamespaces) {
+		return ReasonBlacklistedNamespace, "namespace " + pdt.Namespace + " is blacklisted"
+	}
+
+	if user := requester(pdt); user != "" && cfg.Matches(user, rt.BlacklistUsers) {
+		return ReasonBlacklistedUser, "user " + user + " is blacklisted"
+	}
+
+	return "", ""
+}
+
+// freezeExempt true for products in system namespaces, they are reconciled during a freeze
+func freezeExempt(pdt *pdtv1.Product) bool {
+	return cfg.Matches(pdt.Namespace, cfg.CurrentRuntime().SystemNamespaces)
+}
+
+// block rejects the product instead of reconciling it, the status is only written when the block is new
+// so resyncs and restarts do not repeat the warning
+func block(ctx context.Context, pdtCopy *pdtv1.Product, reason, message string, clients cc.EstoreClientInterface, recorder record.EventRecorder) error {
+	if cond := getCondition(&pdtCopy.Status, ConditionTypeBlocked); cond != nil && cond.Status == pdtv1.ConditionTrue &&
+		cond.Reason == reason && cond.Message == message {
+		gLog.FromContext(ctx).SetObjectState(lc.Ignored).SetStepState(lc.Skip).Infof("process product %s skipped, still blocked: %s", pdtCopy.Name, message)
+		return nil
+	}
+
+	if _, err := writeStatus(ctx, pdtCopy, clients, func(status *pdtv1.ProductStatus) {
+		setCondition(status, ConditionTypeBlocked, pdtv1.ConditionTrue, reason, message)
+		setCondition(status, ConditionTypeReady, pdtv1.ConditionFalse, reason, message)
+		status.CurrentStatus.Phase = pdtv1.ProductFailed
+	}, lastOperation(ctx, pdtCopy, pdtv1.ProductStateFailed, reason, message)); err != nil {
+		handleError(ctx, pdtCopy, err, recorder)
+		return err
+	}
+
+	recorder.Event(pdtCopy, corev1.EventTypeWarning, "Blocked", message)
+	gLog.FromContext(ctx).SetObjectState(lc.Ignored).SetStepState(lc.Skip).Warnf("process product %s blocked: %s", pdtCopy.Name, message)
+
+	return nil
+}
+
+// unblock marks a previously blocked product as allowed
+func unblock(status *pdtv1.ProductStatus) {
+	if getCondition(status, ConditionTypeBlocked) != nil {
+		setCondition(status, ConditionTypeBlocked, pdtv1.ConditionFalse, ReasonAllowed, "product is not blacklisted")
+	}
+}
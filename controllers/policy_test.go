@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+// setConfig sets the config keys and the runtime settings read from them for the test, restoring both afterwards
+func setConfig(t *testing.T, values map[string]string) {
+	rt := cfg.CurrentRuntime()
+
+	t.Cleanup(func() { cfg.SetRuntime(rt) })
+
+	for key, value := range values {
+		old := viper.GetString(key)
+		viper.Set(key, value)
+
+		key := key
+
+		t.Cleanup(func() { viper.Set(key, old) })
+	}
+
+	newRt, err := cfg.RuntimeFromViper(viper.GetViper())
+	if err != nil {
+		t.Fatalf("RuntimeFromViper() error = %v", err)
+	}
+
+	cfg.SetRuntime(newRt)
+}
+
+func makePolicyProduct(namespace, requesterAnnotation, manager string) *pdtv1.Product {
+	pdt := makeProduct(namespace, "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	if requesterAnnotation != "" {
+		pdt.Annotations = map[string]string{pdtv1.ProductAnnotationRequester: requesterAnnotation}
+	}
+
+	if manager != "" {
+		pdt.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate}}
+	}
+
+	return pdt
+}
+
+func Test_blockedBy(t *testing.T) {
+	setConfig(t, map[string]string{
+		"app.blacklist.namespaces": "virus",
+		"app.blacklist.users":      "stranger",
+	})
+
+	tests := []struct {
+		name       string
+		pdt        *pdtv1.Product
+		wantReason string
+	}{
+		{name: "success allowed", pdt: makePolicyProduct("testNs", "", "kubectl"), wantReason: ""},
+		{name: "success blacklisted namespace", pdt: makePolicyProduct("virus", "", ""), wantReason: ReasonBlacklistedNamespace},
+		{name: "success blacklisted namespace prefix", pdt: makePolicyProduct("virus-lab", "", ""), wantReason: ReasonBlacklistedNamespace},
+		{name: "success blacklisted requester annotation", pdt: makePolicyProduct("testNs", "stranger", "kubectl"), wantReason: ReasonBlacklistedUser},
+		{name: "success blacklisted field manager", pdt: makePolicyProduct("testNs", "", "stranger"), wantReason: ReasonBlacklistedUser},
+		{name: "success requester annotation wins over field manager", pdt: makePolicyProduct("testNs", "admin", "stranger"), wantReason: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := blockedBy(tt.pdt); got != tt.wantReason {
+				t.Errorf("blockedBy() = %v, want %v", got, tt.wantReason)
+			}
+		})
+	}
+}
+
+func Test_freezeExempt(t *testing.T) {
+	setConfig(t, map[string]string{"app.system.namespaces": "kube,default"})
+
+	tests := []struct {
+		name string
+		pdt  *pdtv1.Product
+		want bool
+	}{
+		{name: "success system namespace", pdt: makePolicyProduct("default", "", ""), want: true},
+		{name: "success system namespace prefix", pdt: makePolicyProduct("kube-system", "", ""), want: true},
+		{name: "success other namespace", pdt: makePolicyProduct("testNs", "", ""), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := freezeExempt(tt.pdt); got != tt.want {
+				t.Errorf("freezeExempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessItem_blocked(t *testing.T) {
+	setConfig(t, map[string]string{"app.blacklist.namespaces": "virus"})
+
+	pdt := makePolicyProduct("virus", "", "")
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtClient := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace)
+	pdtBackend := backend.NewMemoryBackend()
+	recorder := record.NewFakeRecorder(fakeRecorderSize)
+
+	if _, err := ProcessItem(context.Background(), pdt, fakeClients, pdtBackend, recorder); err != nil {
+		t.Fatalf("ProcessItem() blocked error = %v", err)
+	}
+
+	got, err := pdtClient.Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if cond := getCondition(&got.Status, ConditionTypeBlocked); cond == nil || cond.Status != pdtv1.ConditionTrue ||
+		cond.Reason != ReasonBlacklistedNamespace {
+		t.Errorf("ProcessItem() blocked condition = %+v, want true %s", cond, ReasonBlacklistedNamespace)
+	}
+
+	if got.Status.CurrentStatus.Phase != pdtv1.ProductFailed || len(got.Finalizers) != 0 || pdtBackend.Len() != 0 {
+		t.Errorf("ProcessItem() blocked phase = %v, finalizers = %v, backend = %d, want failed, none, 0",
+			got.Status.CurrentStatus.Phase, got.Finalizers, pdtBackend.Len())
+	}
+
+	if event := <-recorder.Events; event != "Warning Blocked namespace virus is blacklisted" {
+		t.Errorf("ProcessItem() blocked event = %v", event)
+	}
+
+	// still blocked: no status write and no repeated warning
+	if _, err := ProcessItem(context.Background(), got, fakeClients, pdtBackend, recorder); err != nil || len(recorder.Events) != 0 {
+		t.Errorf("ProcessItem() still blocked error = %v, events = %d, want nil, 0", err, len(recorder.Events))
+	}
+
+	// blacklist lifted: product reconciled and unblocked
+	setConfig(t, map[string]string{"app.blacklist.namespaces": ""})
+
+	if _, err := ProcessItem(context.Background(), got, fakeClients, pdtBackend, recorder); err != nil {
+		t.Fatalf("ProcessItem() unblocked error = %v", err)
+	}
+
+	got, err = pdtClient.Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if cond := getCondition(&got.Status, ConditionTypeBlocked); cond == nil || cond.Status != pdtv1.ConditionFalse {
+		t.Errorf("ProcessItem() unblocked condition = %+v, want false", cond)
+	}
+
+	if got.Status.CurrentStatus.Phase != pdtv1.ProductAvailable || pdtBackend.Len() != 1 {
+		t.Errorf("ProcessItem() unblocked phase = %v, backend = %d, want available, 1", got.Status.CurrentStatus.Phase, pdtBackend.Len())
+	}
+}
@@ -0,0 +1,52 @@
+// Package controllers controllers
+package controllers
+
+import (
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+)
+
+// Predicate true when the change of the product from oldPdt must be reconciled, oldPdt is nil when the product
+// was added to the cache
+type Predicate func(oldPdt, pdt *pdtv1.Product) bool
+
+// Predicates products are only enqueued when one of them admits the change, the rest are own status writes and
+// resyncs. Products waiting on the runtime settings are requeued by Controller.RequeueNotReady instead.
+var Predicates = []Predicate{DeletionStarted, GenerationChanged, FinalizersChanged, NotAvailable}
+
+// admit true when one of the predicates admits the change
+func admit(oldPdt, pdt *pdtv1.Product) bool {
+	for _, predicate := range Predicates {
+		if predicate(oldPdt, pdt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeletionStarted admits the deletion of a product whatever its phase, so the backend cleanup runs and the finalizer
+// is removed. Later status writes of the deleting product are not admitted, a dropped cleanup stays dropped.
+func DeletionStarted(oldPdt, pdt *pdtv1.Product) bool {
+	return pdt.DeletionTimestamp != nil && (oldPdt == nil || oldPdt.DeletionTimestamp == nil)
+}
+
+// GenerationChanged admits spec changes, and added products whose generation was not reconciled yet
+func GenerationChanged(oldPdt, pdt *pdtv1.Product) bool {
+	if oldPdt == nil {
+		return observedGeneration(pdt) != pdt.Generation
+	}
+
+	return specChanged(oldPdt, pdt)
+}
+
+// FinalizersChanged admits finalizer changes, such as the operator finalizer being removed by someone else
+func FinalizersChanged(oldPdt, pdt *pdtv1.Product) bool {
+	return oldPdt != nil && !apiequality.Semantic.DeepEqual(oldPdt.Finalizers, pdt.Finalizers)
+}
+
+// NotAvailable admits added products that are not available yet
+func NotAvailable(oldPdt, pdt *pdtv1.Product) bool {
+	return oldPdt == nil && pdt.Status.CurrentStatus.Phase != pdtv1.ProductAvailable
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+func Test_admit(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductAvailable)
+	pdt.Generation = 1
+	pdt.Finalizers = []string{cfg.ProductOperatorFinalizer}
+	pdt.Annotations = map[string]string{cfg.ObservedGenerationAnnotation: "1"}
+
+	pdtDeleting := pdt.DeepCopy()
+	now := metav1.Now()
+	pdtDeleting.DeletionTimestamp = &now
+
+	pdtDeletingStatus := pdtDeleting.DeepCopy()
+	pdtDeletingStatus.Status.CurrentStatus.Phase = pdtv1.ProductDeleting
+
+	pdtSpecChange := pdt.DeepCopy()
+	pdtSpecChange.Generation = 2
+	pdtSpecChange.Spec.Price = 200
+
+	pdtFinalizerRemoved := pdt.DeepCopy()
+	pdtFinalizerRemoved.Finalizers = nil
+
+	pdtStatusOnly := pdt.DeepCopy()
+	pdtStatusOnly.Status.CurrentStatus.TimeoutActive = true
+
+	pdtUnobserved := pdt.DeepCopy()
+	pdtUnobserved.Annotations = nil
+
+	pdtPending := pdt.DeepCopy()
+	pdtPending.Status.CurrentStatus.Phase = pdtv1.ProductPending
+
+	tests := []struct {
+		name   string
+		oldPdt *pdtv1.Product
+		pdt    *pdtv1.Product
+		want   bool
+	}{
+		{name: "success admit deletion of available product", oldPdt: pdt, pdt: pdtDeleting, want: true},
+		{name: "success admit added deleting product", pdt: pdtDeleting, want: true},
+		{name: "success admit spec change of available product", oldPdt: pdt, pdt: pdtSpecChange, want: true},
+		{name: "success admit finalizer removed", oldPdt: pdt, pdt: pdtFinalizerRemoved, want: true},
+		{name: "success admit added unobserved generation", pdt: pdtUnobserved, want: true},
+		{name: "success admit added not available", pdt: pdtPending, want: true},
+		{name: "failure admit status only update", oldPdt: pdt, pdt: pdtStatusOnly, want: false},
+		{name: "failure admit status update of deleting product", oldPdt: pdtDeleting, pdt: pdtDeletingStatus, want: false},
+		{name: "failure admit resync", oldPdt: pdt, pdt: pdt, want: false},
+		{name: "failure admit resync not available", oldPdt: pdtPending, pdt: pdtPending, want: false},
+		{name: "failure admit added available", pdt: pdt, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := admit(tt.oldPdt, tt.pdt); got != tt.want {
+				t.Errorf("admit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
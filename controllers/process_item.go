@@ -2,8 +2,10 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 
 	cc "github.com/arutselvan15/estore-common/clients"
@@ -11,76 +13,222 @@ import (
 	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
 	lc "github.com/arutselvan15/go-utils/logconstants"
 
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
 	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
 )
 
-// ProcessItemType process item type
-type ProcessItemType func(*pdtv1.Product, cc.EstoreClientInterface, record.EventRecorder) error
+// ProcessItemType process item type, the backend and api calls are cancelled once ctx is done. The result asks for
+// the key again without an error.
+type ProcessItemType func(context.Context, *pdtv1.Product, cc.EstoreClientInterface, backend.ProductBackend, record.EventRecorder) (Result, error)
 
 // ProcessItem process item
-func ProcessItem(pdt *pdtv1.Product, clients cc.EstoreClientInterface, recorder record.EventRecorder) error {
-	log.SetObjectState(lc.Processing).SetStep(cfg.ProcessItem).SetStepState(lc.Start).Infof("process product %s start", pdt.Name)
+func ProcessItem(ctx context.Context, pdt *pdtv1.Product, clients cc.EstoreClientInterface, pdtBackend backend.ProductBackend,
+	recorder record.EventRecorder) (Result, error) {
+	logger := gLog.FromContext(ctx)
+	logger.SetObjectState(lc.Processing).SetStep(cfg.ProcessItem).SetStepState(lc.Start).Infof("process product %s start", pdt.Name)
 	pdtCopy := pdt.DeepCopy()
 
 	// examine DeletionTimestamp to determine if object is under deletion
 	if pdtCopy.ObjectMeta.DeletionTimestamp.IsZero() {
-		if err := update(pdtCopy, clients, recorder); err != nil {
-			handleError(pdtCopy, err, recorder)
-			return err
+		// invalid specs are rejected without a requeue, the spec change fixing them triggers the next reconcile
+		if violations := specValidator().Validate(pdtCopy); len(violations) > 0 {
+			return Result{}, invalidate(ctx, pdtCopy, violations, clients, recorder)
+		}
+
+		// blacklisted products are rejected before anything is changed, deletions are always let through
+		if reason, message := blockedBy(pdtCopy); reason != "" {
+			return Result{}, block(ctx, pdtCopy, reason, message, clients, recorder)
+		}
+
+		// changes wait for the freeze window to close
+		if isFrozen, message := frozen(); isFrozen && !freezeExempt(pdtCopy) {
+			return freeze(ctx, pdtCopy, message, clients, recorder)
+		}
+
+		// the finalizer is added before the product reaches the backend, so deleting it always cleans up
+		if !helper.ContainsString(pdtCopy.ObjectMeta.Finalizers, cfg.ProductOperatorFinalizer) {
+			patched, err := addFinalizer(ctx, pdtCopy, clients)
+			if err != nil {
+				recordError(ctx, pdtCopy, ReasonFinalizerPending, err, clients, validSpec, unblock, thaw, func(status *pdtv1.ProductStatus) {
+					setCondition(status, ConditionTypeFinalizer, pdtv1.ConditionFalse, ReasonFinalizerPending,
+						"adding finalizer "+cfg.ProductOperatorFinalizer)
+				})
+				handleError(ctx, pdtCopy, err, recorder)
+
+				return Result{}, err
+			}
+
+			pdtCopy = patched
+		}
+
+		finalizerAdded := func(status *pdtv1.ProductStatus) {
+			setCondition(status, ConditionTypeFinalizer, pdtv1.ConditionTrue, ReasonFinalizerAdded,
+				"finalizer "+cfg.ProductOperatorFinalizer+" present")
+		}
+
+		// nothing to sync when the spec was reconciled already, a lifted block or freeze is still recorded
+		if isObserved(pdtCopy) {
+			if _, err := writeStatus(ctx, pdtCopy, clients, validSpec, unblock, thaw, finalizerAdded); err != nil {
+				handleError(ctx, pdtCopy, err, recorder)
+				return Result{}, err
+			}
+
+			logger.SetObjectState(lc.Ignored).SetStepState(lc.Skip).Infof("process product %s skipped, generation %d already observed", pdt.Name, pdt.Generation)
+
+			return Result{}, nil
 		}
 
-		pdtCopy.Status.CurrentStatus.Phase = pdtv1.ProductAvailable
-		pdtCopy.Status.CurrentStatus.LastUpdateTime = metav1.Now()
-		pdtCopy.Status.LastOperation.LastUpdateTime = metav1.Now()
+		if err := update(ctx, pdtCopy, pdtBackend, recorder); err != nil {
+			recordError(ctx, pdtCopy, ReasonSyncFailed, err, clients, validSpec, unblock, thaw, finalizerAdded, func(status *pdtv1.ProductStatus) {
+				setCondition(status, ConditionTypeBackendReachable, pdtv1.ConditionFalse, ReasonBackendError, err.Error())
+				setCondition(status, ConditionTypeSynced, pdtv1.ConditionFalse, ReasonSyncFailed, err.Error())
+			})
+			handleError(ctx, pdtCopy, err, recorder)
+
+			return Result{}, err
+		}
 
-		if _, err := clients.GetProductClient().EstoreV1().Products(pdtCopy.Namespace).UpdateStatus(pdtCopy); err != nil {
-			handleError(pdtCopy, err, recorder)
-			return err
+		if _, err := writeStatus(ctx, pdtCopy, clients, validSpec, unblock, thaw, finalizerAdded, available,
+			lastOperation(ctx, pdtCopy, pdtv1.ProductStateSuccessful, "product synced to backend", "")); err != nil {
+			handleError(ctx, pdtCopy, err, recorder)
+			return Result{}, err
+		}
+
+		if err := recordObservedGeneration(ctx, pdtCopy, clients); err != nil {
+			handleError(ctx, pdtCopy, err, recorder)
+			return Result{}, err
 		}
 
 		recorder.Event(pdtCopy, corev1.EventTypeNormal, "Phase", "Available")
 	} else if helper.ContainsString(pdtCopy.ObjectMeta.Finalizers, cfg.ProductOperatorFinalizer) {
-		// The object is being deleted
+		// The object is being deleted, the backend cleanup waits for the freeze window unless deletes are allowed
+		if isFrozen, message := frozen(); isFrozen && !freezeExempt(pdtCopy) && !cfg.FreezeAllowDeletes {
+			return freeze(ctx, pdtCopy, message, clients, recorder, deleting)
+		}
+
 		// our finalizer is present, so lets handle any external dependency
-		if err := delete(pdtCopy, clients, recorder); err != nil {
+		if err := delete(ctx, pdtCopy, pdtBackend, recorder); err != nil {
 			// fail to delete the external dependency here, return with error so that it can be retried
-			handleError(pdtCopy, err, recorder)
-			return err
+			recordError(ctx, pdtCopy, ReasonDeleteFailed, err, clients, thaw, deleting, func(status *pdtv1.ProductStatus) {
+				setCondition(status, ConditionTypeBackendReachable, pdtv1.ConditionFalse, ReasonBackendError, err.Error())
+			})
+			handleError(ctx, pdtCopy, err, recorder)
+
+			return Result{}, err
 		}
 
 		// remove our finalizer from the list and update it.
-		pdtCopy.ObjectMeta.Finalizers = helper.RemoveString(pdtCopy.ObjectMeta.Finalizers, cfg.ProductOperatorFinalizer)
-		pdtCopy.Status.CurrentStatus.LastUpdateTime = metav1.Now()
-		pdtCopy.Status.LastOperation.LastUpdateTime = metav1.Now()
-
-		if _, err := clients.GetProductClient().EstoreV1().Products(pdtCopy.Namespace).Update(pdtCopy); err != nil {
-			handleError(pdtCopy, err, recorder)
-			return err
+		if _, err := removeFinalizer(ctx, pdtCopy, clients); err != nil {
+			handleError(ctx, pdtCopy, err, recorder)
+			return Result{}, err
 		}
 
 		recorder.Event(pdtCopy, corev1.EventTypeNormal, "Phase", "Deleted")
 	}
 
-	log.SetObjectState(lc.Successful).SetStepState(lc.Complete).Infof("process product %s completed successfully", pdt.Name)
+	logger.SetObjectState(lc.Successful).SetStepState(lc.Complete).Infof("process product %s completed successfully", pdt.Name)
 
-	return nil
+	return Result{}, nil
 }
 
-func update(pdtCopy *pdtv1.Product, clients cc.EstoreClientInterface, recorder record.EventRecorder) error {
-	log.SetStepState(lc.Processing).Debugf("processing pdt %s", pdtCopy.Name)
+func update(ctx context.Context, pdtCopy *pdtv1.Product, pdtBackend backend.ProductBackend, recorder record.EventRecorder) error {
+	gLog.FromContext(ctx).SetStepState(lc.Processing).Debugf("processing pdt %s", pdtCopy.Name)
+
+	if err := pdtBackend.Upsert(ctx, pdtCopy); err != nil {
+		return err
+	}
+
+	recorder.Event(pdtCopy, corev1.EventTypeNormal, "Backend", "Synced")
 
 	return nil
 }
 
-func delete(pdtCopy *pdtv1.Product, clients cc.EstoreClientInterface, recorder record.EventRecorder) error {
-	log.SetStepState(lc.Processing).Debugf("processing pdt %s", pdtCopy.Name)
+func delete(ctx context.Context, pdtCopy *pdtv1.Product, pdtBackend backend.ProductBackend, recorder record.EventRecorder) error {
+	gLog.FromContext(ctx).SetStepState(lc.Processing).Debugf("processing pdt %s", pdtCopy.Name)
+
+	if err := pdtBackend.Delete(ctx, pdtCopy); err != nil {
+		return err
+	}
+
+	recorder.Event(pdtCopy, corev1.EventTypeNormal, "Backend", "Deleted")
 
 	return nil
 }
 
-func handleError(pdtCopy *pdtv1.Product, err error, recorder record.EventRecorder) {
-	recorder.Event(pdtCopy, corev1.EventTypeWarning, "Reason", err.Error())
-	recorder.Event(pdtCopy, corev1.EventTypeWarning, "Phase", "Unavailable")
-	log.SetStepState(lc.Error).Error(err.Error())
-	log.SetStepState(lc.Retry).Debugf("process product %s failed, re-queued for retry", pdtCopy.Name)
+// available marks the product synced and available
+func available(status *pdtv1.ProductStatus) {
+	if getCondition(status, ConditionTypeFailed) != nil {
+		setCondition(status, ConditionTypeFailed, pdtv1.ConditionFalse, ReasonSynced, "product synced to backend")
+	}
+
+	setCondition(status, ConditionTypeBackendReachable, pdtv1.ConditionTrue, ReasonReachable, "backend call succeeded")
+	setCondition(status, ConditionTypeSynced, pdtv1.ConditionTrue, ReasonSynced, "product synced to backend")
+	setCondition(status, ConditionTypeReady, pdtv1.ConditionTrue, ReasonAvailable, "product available")
+
+	status.CurrentStatus.Phase = pdtv1.ProductAvailable
+}
+
+// deleting marks the product as being removed from the backend
+func deleting(status *pdtv1.ProductStatus) {
+	setCondition(status, ConditionTypeDeleting, pdtv1.ConditionTrue, ReasonDeleting, "removing product from backend")
+	setCondition(status, ConditionTypeReady, pdtv1.ConditionFalse, ReasonDeleting, "product is being deleted")
+	status.CurrentStatus.Phase = pdtv1.ProductDeleting
+}
+
+// recordError records the failure along with the changes on the product status so it is visible on the object,
+// also when ctx timed out. A failing status write is only logged as the original error is returned for retry anyway.
+// A reconcile cancelled on shutdown did not fail, the status is left to the next leader.
+func recordError(ctx context.Context, pdtCopy *pdtv1.Product, reason string, err error, clients cc.EstoreClientInterface,
+	changes ...statusChange) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	isDeleting := !pdtCopy.ObjectMeta.DeletionTimestamp.IsZero()
+
+	if errorKind(err) == ErrorKindTimeout {
+		reason = ReasonTimeout
+	}
+
+	changes = append(changes, func(status *pdtv1.ProductStatus) {
+		setCondition(status, ConditionTypeReady, pdtv1.ConditionFalse, reason, err.Error())
+
+		if !isDeleting {
+			status.CurrentStatus.Phase = pdtv1.ProductUnknown
+		}
+	}, lastOperation(ctx, pdtCopy, pdtv1.ProductStateFailed, reason, err.Error()))
+
+	if _, updateErr := writeStatus(context.WithoutCancel(ctx), pdtCopy, clients, changes...); updateErr != nil {
+		gLog.FromContext(ctx).SetStepState(lc.Error).Errorf("recording failure on product %s status failed: %v", pdtCopy.Name, updateErr)
+	}
+}
+
+func handleError(ctx context.Context, pdtCopy *pdtv1.Product, err error, recorder record.EventRecorder) {
+	logger := gLog.FromContext(ctx)
+
+	if errors.Is(err, context.Canceled) {
+		logger.SetStepState(lc.Skip).Infof("process product %s cancelled on shutdown, left for the next leader", pdtCopy.Name)
+		return
+	}
+
+	switch errorKind(err) {
+	case ErrorKindConflict:
+		// expected under concurrent writes, retried with backoff and a fresh read, counting against cfg.MaxRetries
+		logger.SetStepState(lc.Retry).Debugf("process product %s conflicted, re-queued with a fresh read: %v", pdtCopy.Name, err)
+	case ErrorKindTimeout:
+		recorder.Event(pdtCopy, corev1.EventTypeWarning, ReasonTimeout, err.Error())
+		recorder.Event(pdtCopy, corev1.EventTypeWarning, "Phase", "Unavailable")
+		logger.SetStepState(lc.Error).Errorf("process product %s timed out after %s: %v", pdtCopy.Name, cfg.ReconcileTimeout, err)
+		logger.SetStepState(lc.Retry).Debugf("process product %s timed out, re-queued for retry", pdtCopy.Name)
+	case ErrorKindPermanent:
+		recorder.Event(pdtCopy, corev1.EventTypeWarning, "Reason", err.Error())
+		recorder.Event(pdtCopy, corev1.EventTypeWarning, "Phase", "Failed")
+		logger.SetStepState(lc.Error).Errorf("process product %s failed permanently, not retried: %v", pdtCopy.Name, err)
+	default:
+		recorder.Event(pdtCopy, corev1.EventTypeWarning, "Reason", err.Error())
+		recorder.Event(pdtCopy, corev1.EventTypeWarning, "Phase", "Unavailable")
+		logger.SetStepState(lc.Error).Error(err.Error())
+		logger.SetStepState(lc.Retry).Debugf("process product %s failed, re-queued for retry", pdtCopy.Name)
+	}
 }
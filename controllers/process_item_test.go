@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
@@ -10,6 +14,9 @@ import (
 	"github.com/arutselvan15/estore-common/clients"
 	fakecc "github.com/arutselvan15/estore-common/clients/fake"
 	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
 )
 
 func makeProduct(namespace, name, brand string, price float64, categories []string, phase pdtv1.ProductPhase) *pdtv1.Product {
@@ -26,10 +33,35 @@ func makeTestProduct() *pdtv1.Product {
 	return makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductAvailable)
 }
 
+// failingBackend backend failing every call
+type failingBackend struct{}
+
+func (failingBackend) Upsert(ctx context.Context, pdt *pdtv1.Product) error {
+	return errors.New("backend unavailable")
+}
+
+func (failingBackend) Delete(ctx context.Context, pdt *pdtv1.Product) error {
+	return errors.New("backend unavailable")
+}
+
+// hangingBackend backend whose calls only return once ctx is done
+type hangingBackend struct{}
+
+func (hangingBackend) Upsert(ctx context.Context, pdt *pdtv1.Product) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (hangingBackend) Delete(ctx context.Context, pdt *pdtv1.Product) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
 func TestProcessItem(t *testing.T) {
 	type args struct {
 		pdt      *pdtv1.Product
 		clients  clients.EstoreClientInterface
+		backend  backend.ProductBackend
 		recorder record.EventRecorder
 	}
 
@@ -44,13 +76,14 @@ func TestProcessItem(t *testing.T) {
 		args    args
 		wantErr bool
 	}{
-		{name: "success process item update", args: args{pdt: pdt, clients: fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil), recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: false},
-		{name: "success process item delete", args: args{pdt: pdtDelete, clients: fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil), recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: false},
+		{name: "success process item update", args: args{pdt: pdt, clients: fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil), backend: backend.NewMemoryBackend(), recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: false},
+		{name: "success process item delete", args: args{pdt: pdtDelete, clients: fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil), backend: backend.NewMemoryBackend(), recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: false},
+		{name: "failure process item backend unavailable", args: args{pdt: pdt, clients: fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil), backend: failingBackend{}, recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := ProcessItem(tt.args.pdt, tt.args.clients, tt.args.recorder); (err != nil) != tt.wantErr {
+			if _, err := ProcessItem(context.Background(), tt.args.pdt, tt.args.clients, tt.args.backend, tt.args.recorder); (err != nil) != tt.wantErr {
 				t.Errorf("ProcessItem() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -60,7 +93,7 @@ func TestProcessItem(t *testing.T) {
 func Test_delete(t *testing.T) {
 	type args struct {
 		pdtCopy  *pdtv1.Product
-		clients  clients.EstoreClientInterface
+		backend  backend.ProductBackend
 		recorder record.EventRecorder
 	}
 
@@ -69,12 +102,13 @@ func Test_delete(t *testing.T) {
 		args    args
 		wantErr bool
 	}{
-		{name: "success product create", args: args{pdtCopy: makeTestProduct(), clients: fakecc.NewEstoreFakeClientForConfig(nil, nil), recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: false},
+		{name: "success product delete", args: args{pdtCopy: makeTestProduct(), backend: backend.NewMemoryBackend(), recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: false},
+		{name: "failure product delete backend unavailable", args: args{pdtCopy: makeTestProduct(), backend: failingBackend{}, recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := delete(tt.args.pdtCopy, tt.args.clients, tt.args.recorder); (err != nil) != tt.wantErr {
+			if err := delete(context.Background(), tt.args.pdtCopy, tt.args.backend, tt.args.recorder); (err != nil) != tt.wantErr {
 				t.Errorf("delete() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -84,7 +118,7 @@ func Test_delete(t *testing.T) {
 func Test_update(t *testing.T) {
 	type args struct {
 		pdtCopy  *pdtv1.Product
-		clients  clients.EstoreClientInterface
+		backend  backend.ProductBackend
 		recorder record.EventRecorder
 	}
 
@@ -93,14 +127,98 @@ func Test_update(t *testing.T) {
 		args    args
 		wantErr bool
 	}{
-		{name: "success product delete", args: args{pdtCopy: makeTestProduct(), clients: fakecc.NewEstoreFakeClientForConfig(nil, nil), recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: false},
+		{name: "success product update", args: args{pdtCopy: makeTestProduct(), backend: backend.NewMemoryBackend(), recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: false},
+		{name: "failure product update backend unavailable", args: args{pdtCopy: makeTestProduct(), backend: failingBackend{}, recorder: record.NewFakeRecorder(fakeRecorderSize)}, wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := update(tt.args.pdtCopy, tt.args.clients, tt.args.recorder); (err != nil) != tt.wantErr {
+			if err := update(context.Background(), tt.args.pdtCopy, tt.args.backend, tt.args.recorder); (err != nil) != tt.wantErr {
 				t.Errorf("update() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestProcessItem_conditions(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductUnknown)
+
+	pdtDelete := pdt.DeepCopy()
+	now := metav1.Now()
+	pdtDelete.ObjectMeta.DeletionTimestamp = &now
+	pdtDelete.ObjectMeta.Finalizers = []string{cfg.ProductOperatorFinalizer}
+
+	tests := []struct {
+		name      string
+		pdt       *pdtv1.Product
+		backend   backend.ProductBackend
+		wantPhase pdtv1.ProductPhase
+		want      map[pdtv1.ProductConditionType]pdtv1.ConditionStatus
+	}{
+		{
+			name: "success conditions available", pdt: pdt, backend: backend.NewMemoryBackend(), wantPhase: pdtv1.ProductAvailable,
+			want: map[pdtv1.ProductConditionType]pdtv1.ConditionStatus{ConditionTypeReady: pdtv1.ConditionTrue, ConditionTypeSynced: pdtv1.ConditionTrue, ConditionTypeBackendReachable: pdtv1.ConditionTrue},
+		},
+		{
+			name: "success conditions sync failed", pdt: pdt, backend: failingBackend{}, wantPhase: pdtv1.ProductUnknown,
+			want: map[pdtv1.ProductConditionType]pdtv1.ConditionStatus{ConditionTypeReady: pdtv1.ConditionFalse, ConditionTypeSynced: pdtv1.ConditionFalse, ConditionTypeBackendReachable: pdtv1.ConditionFalse},
+		},
+		{
+			name: "success conditions delete failed", pdt: pdtDelete, backend: failingBackend{}, wantPhase: pdtv1.ProductDeleting,
+			want: map[pdtv1.ProductConditionType]pdtv1.ConditionStatus{ConditionTypeReady: pdtv1.ConditionFalse, ConditionTypeDeleting: pdtv1.ConditionTrue, ConditionTypeBackendReachable: pdtv1.ConditionFalse},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{tt.pdt}, nil)
+			_, _ = ProcessItem(context.Background(), tt.pdt, fakeClients, tt.backend, record.NewFakeRecorder(fakeRecorderSize))
+
+			got, err := fakeClients.GetProductClient().EstoreV1().Products(tt.pdt.Namespace).Get(tt.pdt.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if got.Status.CurrentStatus.Phase != tt.wantPhase {
+				t.Errorf("ProcessItem() phase = %v, want %v", got.Status.CurrentStatus.Phase, tt.wantPhase)
+			}
+
+			for condType, condStatus := range tt.want {
+				cond := getCondition(&got.Status, condType)
+				if cond == nil || cond.Status != condStatus || cond.Reason == "" || cond.LastTransitionTime == "" {
+					t.Errorf("ProcessItem() condition %s = %+v, want status %v", condType, cond, condStatus)
+				}
+			}
+		})
+	}
+}
+
+// TestProcessItem_cancelled a reconcile cancelled on shutdown leaves the status and events to the next leader
+func TestProcessItem_cancelled(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	pdt.Finalizers = []string{cfg.ProductOperatorFinalizer}
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	recorder := record.NewFakeRecorder(fakeRecorderSize)
+
+	if _, err := ProcessItem(context.Background(), pdt, fakeClients, errorBackend{err: context.Canceled}, recorder); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ProcessItem() error = %v, want %v", err, context.Canceled)
+	}
+
+	got, err := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace).Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if got.Status.CurrentStatus.Phase != pdtv1.ProductPending || len(got.Status.Conditions) != 0 || got.Status.LastOperation.Type != "" {
+		t.Errorf("ProcessItem() status = %+v, want unchanged", got.Status)
+	}
+
+	close(recorder.Events)
+
+	for event := range recorder.Events {
+		if strings.HasPrefix(event, corev1.EventTypeWarning) {
+			t.Errorf("ProcessItem() event = %v, want no warnings", event)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+// QuarantinePath debug http path listing the quarantined keys
+const QuarantinePath = "/debug/quarantine"
+
+// PanicError panic of a reconcile recovered by the worker
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("reconcile panicked: %v", e.Value)
+}
+
+// QuarantinedKey key no longer reconciled after its reconciles panicked cfg.MaxPanics times in a row
+type QuarantinedKey struct {
+	Key    string    `json:"key"`
+	Panics int       `json:"panics"`
+	Since  time.Time `json:"since"`
+	Panic  string    `json:"panic"`
+	Stack  string    `json:"stack"`
+
+	// release value of the release annotation when the key was quarantined, a new value releases it
+	release string
+}
+
+// quarantine panics in a row of the keys and the keys quarantined for them. A key is only changed by the worker
+// reconciling it, or released while it is quarantined and so not reconciled.
+type quarantine struct {
+	// panics panics in a row by key
+	panics sync.Map
+	// keys quarantined keys
+	keys sync.Map
+}
+
+// recordPanic counts the panic against the key and returns true when the key is quarantined for it, release is
+// the value of the release annotation of the product of the key
+func (q *quarantine) recordPanic(key string, panicErr *PanicError, release string) bool {
+	panics := 1
+	if previous, ok := q.panics.Load(key); ok {
+		panics += previous.(int)
+	}
+
+	q.panics.Store(key, panics)
+
+	if panics < cfg.MaxPanics {
+		return false
+	}
+
+	q.keys.Store(key, &QuarantinedKey{Key: key, Panics: panics, Since: time.Now(), Panic: fmt.Sprint(panicErr.Value),
+		Stack: string(panicErr.Stack), release: release})
+
+	return true
+}
+
+// recordSuccess forgets the panics of the key, only panics in a row quarantine it
+func (q *quarantine) recordSuccess(key string) {
+	q.panics.Delete(key)
+}
+
+// contains true when the key is quarantined
+func (q *quarantine) contains(key string) bool {
+	_, ok := q.keys.Load(key)
+
+	return ok
+}
+
+// release releases the quarantined key of the product when its release annotation changed since it was
+// quarantined, returns true when the key was released
+func (q *quarantine) release(key string, pdt *pdtv1.Product) bool {
+	quarantined, ok := q.keys.Load(key)
+	if !ok {
+		return false
+	}
+
+	value, annotated := pdt.Annotations[cfg.ReleaseQuarantineAnnotation]
+	if !annotated || value == quarantined.(*QuarantinedKey).release {
+		return false
+	}
+
+	q.keys.Delete(key)
+	q.panics.Delete(key)
+
+	return true
+}
+
+// list quarantined keys sorted by key
+func (q *quarantine) list() []QuarantinedKey {
+	keys := []QuarantinedKey{}
+
+	q.keys.Range(func(_, quarantined interface{}) bool {
+		keys = append(keys, *quarantined.(*QuarantinedKey))
+		return true
+	})
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+
+	return keys
+}
+
+// recoverPanic turns a panic of the reconcile into a PanicError carrying the stack
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+// QuarantineHandler responds with the quarantined keys as json
+func (c *Controller) QuarantineHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "quarantine is read only", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.quarantine.list())
+	})
+}
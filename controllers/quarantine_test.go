@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/arutselvan15/estore-common/clients"
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	pdtInformers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
+)
+
+func TestController_processNextItem_panic(t *testing.T) {
+	maxPanics := cfg.MaxPanics
+	defer func() { cfg.MaxPanics = maxPanics }()
+
+	cfg.MaxPanics = 2
+
+	pdt := makeProduct("testNs", "testPdt", "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	key := pdt.Namespace + "/" + pdt.Name
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtInformer := pdtInformers.NewSharedInformerFactory(fakeClients.GetProductClient(), cfg.ResyncDuration).Estore().V1().Products()
+	_ = pdtInformer.Informer().GetIndexer().Add(pdt)
+
+	reconciles := 0
+	c := &Controller{
+		pdtLister: pdtInformer.Lister(),
+		pdtQueue:  workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(0, 0), "test"),
+		clients:   fakeClients,
+		recorder:  record.NewFakeRecorder(fakeRecorderSize),
+		processItem: func(_ context.Context, _ *pdtv1.Product, _ clients.EstoreClientInterface, _ backend.ProductBackend, _ record.EventRecorder) (Result, error) {
+			reconciles++
+			panic("malformed product")
+		},
+	}
+	defer c.pdtQueue.ShutDown()
+
+	c.pdtQueue.Add(key)
+
+	// the first panic is retried, the second quarantines the key
+	for i := 0; i < cfg.MaxPanics; i++ {
+		if !c.processNextItem(context.Background()) {
+			t.Fatalf("processNextItem() = false after panic %d, want true", i+1)
+		}
+	}
+
+	if c.pdtQueue.Len() != 0 || !c.quarantine.contains(key) {
+		t.Fatalf("processNextItem() queue length = %d quarantined = %v, want 0 true", c.pdtQueue.Len(), c.quarantine.contains(key))
+	}
+
+	got, err := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace).Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if cond := getCondition(&got.Status, ConditionTypeFailed); cond == nil || cond.Reason != ReasonQuarantined {
+		t.Errorf("processNextItem() failed condition = %+v, want reason %s", cond, ReasonQuarantined)
+	}
+
+	// the quarantined key is listed with the panic and its stack
+	rec := httptest.NewRecorder()
+	c.QuarantineHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, QuarantinePath, nil))
+
+	var listed []QuarantinedKey
+	if err = json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("GET %s error = %v", QuarantinePath, err)
+	}
+
+	if len(listed) != 1 || listed[0].Key != key || listed[0].Panics != 2 || listed[0].Panic != "malformed product" ||
+		!strings.Contains(listed[0].Stack, "panic") {
+		t.Errorf("GET %s = %+v, want %s quarantined after 2 panics with the stack", QuarantinePath, listed, key)
+	}
+
+	// a quarantined key is not reconciled again
+	c.pdtQueue.Add(key)
+	c.processNextItem(context.Background())
+
+	if reconciles != cfg.MaxPanics {
+		t.Errorf("processNextItem() reconciles = %d, want %d", reconciles, cfg.MaxPanics)
+	}
+
+	// setting the release annotation releases the key
+	released := pdt.DeepCopy()
+	released.Annotations = map[string]string{cfg.ReleaseQuarantineAnnotation: "1"}
+
+	if got := c.releaseQuarantined(released, gLog.NewLogger()); got != key {
+		t.Fatalf("releaseQuarantined() = %q, want %q", got, key)
+	}
+
+	c.pdtQueue.Add(key)
+	c.processNextItem(context.Background())
+
+	if reconciles != cfg.MaxPanics+1 {
+		t.Errorf("processNextItem() reconciles after release = %d, want %d", reconciles, cfg.MaxPanics+1)
+	}
+}
+
+func Test_quarantine_release(t *testing.T) {
+	pdt := makeTestProduct()
+	key := pdt.Namespace + "/" + pdt.Name
+
+	annotated := func(value string) *pdtv1.Product {
+		pdtCopy := pdt.DeepCopy()
+		pdtCopy.Annotations = map[string]string{cfg.ReleaseQuarantineAnnotation: value}
+
+		return pdtCopy
+	}
+
+	tests := []struct {
+		name        string
+		quarantined bool
+		release     string
+		pdt         *pdtv1.Product
+		want        bool
+	}{
+		{name: "success release new annotation value", quarantined: true, pdt: annotated("1"), want: true},
+		{name: "success release changed annotation value", quarantined: true, release: "1", pdt: annotated("2"), want: true},
+		{name: "failure release annotation value unchanged", quarantined: true, release: "1", pdt: annotated("1"), want: false},
+		{name: "failure release without annotation", quarantined: true, pdt: pdt, want: false},
+		{name: "failure release key not quarantined", pdt: annotated("1"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &quarantine{}
+			if tt.quarantined {
+				for i := 0; i < cfg.MaxPanics; i++ {
+					q.recordPanic(key, &PanicError{Value: "boom"}, tt.release)
+				}
+			}
+
+			if got := q.release(key, tt.pdt); got != tt.want {
+				t.Errorf("release() = %v, want %v", got, tt.want)
+			}
+
+			if q.contains(key) != (tt.quarantined && !tt.want) {
+				t.Errorf("release() quarantined = %v, want %v", q.contains(key), tt.quarantined && !tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// NewRateLimiter per key exponential backoff from baseDelay up to maxDelay, with keys added back to the queue
+// at qps overall with burst
+func NewRateLimiter(baseDelay, maxDelay time.Duration, qps float64, burst int) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(baseDelay, maxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	rateLimiter := NewRateLimiter(10*time.Millisecond, 30*time.Millisecond, 1000, 100)
+
+	for i, want := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 30 * time.Millisecond, 30 * time.Millisecond} {
+		if got := rateLimiter.When("testNs/testPdt"); got != want {
+			t.Errorf("When() failure %d = %v, want %v", i+1, got, want)
+		}
+	}
+
+	if got := rateLimiter.NumRequeues("testNs/testPdt"); got != 4 {
+		t.Errorf("NumRequeues() = %d, want 4", got)
+	}
+
+	rateLimiter.Forget("testNs/testPdt")
+
+	if got := rateLimiter.When("testNs/testPdt"); got != 10*time.Millisecond {
+		t.Errorf("When() after forget = %v, want %v", got, 10*time.Millisecond)
+	}
+}
@@ -0,0 +1,19 @@
+// Package controllers controllers
+package controllers
+
+import "time"
+
+// Result outcome of a reconcile that did not fail. The zero Result completes the key, a reconcile checking back
+// later asks for the key again through Requeue or RequeueAfter instead of returning an error, which would grow
+// its retry backoff. The result of a failed reconcile is ignored, the key is retried by the error.
+type Result struct {
+	// Requeue reconcile the key again right away
+	Requeue bool
+	// RequeueAfter reconcile the key again after the duration, takes precedence over Requeue
+	RequeueAfter time.Duration
+}
+
+// IsZero true when the result completes the key
+func (r Result) IsZero() bool {
+	return !r.Requeue && r.RequeueAfter <= 0
+}
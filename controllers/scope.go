@@ -0,0 +1,183 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	pdtClient "github.com/arutselvan15/estore-product-kube-client/pkg/client/clientset/versioned"
+	pdtInformers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions"
+	pdtv1Informers "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions/estore/v1"
+	pdtv1Listers "github.com/arutselvan15/estore-product-kube-client/pkg/client/listers/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+// Scope restricts the products watched by the controller, the zero value watches all products
+type Scope struct {
+	// Namespaces watched, all namespaces when empty
+	Namespaces []string
+	// LabelSelector products must match
+	LabelSelector labels.Selector
+	// FieldSelector products must match
+	FieldSelector fields.Selector
+}
+
+// NewScope scope for the namespaces and selectors, empty selectors match everything
+func NewScope(namespaces []string, labelSelector, fieldSelector string) (Scope, error) {
+	s := Scope{Namespaces: namespaces, LabelSelector: labels.Everything(), FieldSelector: fields.Everything()}
+
+	var err error
+
+	if labelSelector != "" {
+		if s.LabelSelector, err = labels.Parse(labelSelector); err != nil {
+			return s, fmt.Errorf("invalid label selector %q: %v", labelSelector, err)
+		}
+	}
+
+	if fieldSelector != "" {
+		if s.FieldSelector, err = fields.ParseSelector(fieldSelector); err != nil {
+			return s, fmt.Errorf("invalid field selector %q: %v", fieldSelector, err)
+		}
+	}
+
+	return s, nil
+}
+
+// NewInformerFactories one informer factory per watched namespace, or a single one for all namespaces,
+// listing and watching only the products matching the selectors
+func (s Scope) NewInformerFactories(client pdtClient.Interface, resync time.Duration) []pdtInformers.SharedInformerFactory {
+	tweak := func(options *metav1.ListOptions) {
+		if s.LabelSelector != nil && !s.LabelSelector.Empty() {
+			options.LabelSelector = s.LabelSelector.String()
+		}
+
+		if s.FieldSelector != nil && !s.FieldSelector.Empty() {
+			options.FieldSelector = s.FieldSelector.String()
+		}
+	}
+
+	namespaces := s.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	factories := make([]pdtInformers.SharedInformerFactory, 0, len(namespaces))
+	for _, ns := range namespaces {
+		factories = append(factories, pdtInformers.NewSharedInformerFactoryWithOptions(client, resync,
+			pdtInformers.WithNamespace(ns), pdtInformers.WithTweakListOptions(tweak)))
+	}
+
+	return factories
+}
+
+// Contains true when the product is in a watched namespace and matches the selectors
+func (s Scope) Contains(pdt *pdtv1.Product) bool {
+	if !s.containsNamespace(pdt.Namespace) {
+		return false
+	}
+
+	if s.LabelSelector != nil && !s.LabelSelector.Matches(labels.Set(pdt.Labels)) {
+		return false
+	}
+
+	if s.FieldSelector != nil && !s.FieldSelector.Matches(fields.Set{
+		cfg.FieldName:      pdt.Name,
+		cfg.FieldNamespace: pdt.Namespace,
+	}) {
+		return false
+	}
+
+	return true
+}
+
+func (s Scope) containsNamespace(namespace string) bool {
+	if len(s.Namespaces) == 0 {
+		return true
+	}
+
+	for _, ns := range s.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scopedLister lister over the informers of each watched namespace
+type scopedLister struct {
+	// listers by namespace, a single lister with key metav1.NamespaceAll when all namespaces are watched
+	listers map[string]pdtv1Listers.ProductLister
+}
+
+func newScopedLister(pdtInformers []pdtv1Informers.ProductInformer, namespaces []string) pdtv1Listers.ProductLister {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	l := &scopedLister{listers: map[string]pdtv1Listers.ProductLister{}}
+	for i, pdtInformer := range pdtInformers {
+		l.listers[namespaces[i]] = pdtInformer.Lister()
+	}
+
+	return l
+}
+
+func (l *scopedLister) List(selector labels.Selector) ([]*pdtv1.Product, error) {
+	var ret []*pdtv1.Product
+
+	for _, lister := range l.listers {
+		pdts, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, pdts...)
+	}
+
+	return ret, nil
+}
+
+func (l *scopedLister) Products(namespace string) pdtv1Listers.ProductNamespaceLister {
+	if lister, ok := l.listers[metav1.NamespaceAll]; ok {
+		return lister.Products(namespace)
+	}
+
+	if lister, ok := l.listers[namespace]; ok {
+		return lister.Products(namespace)
+	}
+
+	return emptyNamespaceLister{}
+}
+
+// emptyNamespaceLister lister of a namespace that is not watched
+type emptyNamespaceLister struct{}
+
+func (emptyNamespaceLister) List(selector labels.Selector) ([]*pdtv1.Product, error) {
+	return nil, nil
+}
+
+func (emptyNamespaceLister) Get(name string) (*pdtv1.Product, error) {
+	return nil, errors.NewNotFound(pdtv1.Resource("product"), name)
+}
+
+// informersSynced true once the caches of all informers are synced
+func informersSynced(pdtInformers []pdtv1Informers.ProductInformer) cache.InformerSynced {
+	return func() bool {
+		for _, pdtInformer := range pdtInformers {
+			if !pdtInformer.Informer().HasSynced() {
+				return false
+			}
+		}
+
+		return true
+	}
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	v1 "github.com/arutselvan15/estore-product-kube-client/pkg/client/informers/externalversions/estore/v1"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+func makeScopedProduct(namespace, name string, labels map[string]string) *pdtv1.Product {
+	pdt := makeProduct(namespace, name, "testBrand", 100, []string{"test"}, pdtv1.ProductPending)
+	pdt.Labels = labels
+
+	return pdt
+}
+
+func TestNewScope(t *testing.T) {
+	type args struct {
+		labelSelector string
+		fieldSelector string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{name: "success empty selectors", args: args{}, wantErr: false},
+		{name: "success selectors", args: args{labelSelector: "tier=gold", fieldSelector: "metadata.name=testPdt"}, wantErr: false},
+		{name: "failure invalid label selector", args: args{labelSelector: "tier in gold"}, wantErr: true},
+		{name: "failure invalid field selector", args: args{fieldSelector: "metadata.name"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewScope(nil, tt.args.labelSelector, tt.args.fieldSelector); (err != nil) != tt.wantErr {
+				t.Errorf("NewScope() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScope_Contains(t *testing.T) {
+	type args struct {
+		namespaces    []string
+		labelSelector string
+		fieldSelector string
+	}
+
+	pdt := makeScopedProduct("testNs", "testPdt", map[string]string{"tier": "gold"})
+
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{name: "success all products", args: args{}, want: true},
+		{name: "success watched namespace", args: args{namespaces: []string{"otherNs", "testNs"}}, want: true},
+		{name: "success matching labels", args: args{labelSelector: "tier in (gold,silver)"}, want: true},
+		{name: "success matching fields", args: args{fieldSelector: "metadata.name=testPdt,metadata.namespace=testNs"}, want: true},
+		{name: "failure other namespace", args: args{namespaces: []string{"otherNs"}}, want: false},
+		{name: "failure labels not matching", args: args{labelSelector: "tier=silver"}, want: false},
+		{name: "failure fields not matching", args: args{fieldSelector: "metadata.name!=testPdt"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewScope(tt.args.namespaces, tt.args.labelSelector, tt.args.fieldSelector)
+			if err != nil {
+				t.Fatalf("NewScope() error = %v", err)
+			}
+
+			if got := s.Contains(pdt); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScope_watch(t *testing.T) {
+	inScope := makeScopedProduct("testNs", "testPdt", map[string]string{"tier": "gold"})
+	otherNs := makeScopedProduct("otherNs", "testPdt", map[string]string{"tier": "gold"})
+	otherLabel := makeScopedProduct("testNs", "otherPdt", map[string]string{"tier": "silver"})
+	secondNs := makeScopedProduct("secondNs", "testPdt", map[string]string{"tier": "gold"})
+
+	scope, err := NewScope([]string{"testNs", "secondNs"}, "tier=gold", "")
+	if err != nil {
+		t.Fatalf("NewScope() error = %v", err)
+	}
+
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{inScope, otherNs, otherLabel, secondNs}, nil)
+
+	pdtInformerFactories := scope.NewInformerFactories(fakeClients.GetProductClient(), cfg.ResyncDuration)
+	if len(pdtInformerFactories) != 2 {
+		t.Fatalf("NewInformerFactories() = %d factories, want 2", len(pdtInformerFactories))
+	}
+
+	pdtInformers := make([]v1.ProductInformer, 0, len(pdtInformerFactories))
+	for _, pdtInformerFactory := range pdtInformerFactories {
+		pdtInformers = append(pdtInformers, pdtInformerFactory.Estore().V1().Products())
+	}
+
+	pdtQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+	c := NewController(pdtInformers, scope, pdtQueue, fakeClients, backend.NewMemoryBackend(), record.NewFakeRecorder(fakeRecorderSize), ProcessItem)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	for _, pdtInformerFactory := range pdtInformerFactories {
+		pdtInformerFactory.Start(stopCh)
+	}
+
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return c.pdtListerSynced(), nil
+	}); err != nil {
+		t.Fatalf("cache sync error = %v", err)
+	}
+
+	pdts, err := c.Lister().List(scope.LabelSelector)
+	if err != nil || len(pdts) != 2 {
+		t.Errorf("List() = %d products, error %v, want 2", len(pdts), err)
+	}
+
+	if _, err := c.Lister().Products("otherNs").Get("testPdt"); err == nil {
+		t.Errorf("Products(otherNs).Get() error = nil, want not found")
+	}
+
+	if got := pdtQueue.Len(); got != 2 {
+		t.Errorf("queue length = %d, want 2", got)
+	}
+
+	for pdtQueue.Len() > 0 {
+		key, _ := pdtQueue.Get()
+		if key != "testNs/testPdt" && key != "secondNs/testPdt" {
+			t.Errorf("out of scope key %v enqueued", key)
+		}
+
+		pdtQueue.Done(key)
+	}
+}
@@ -0,0 +1,134 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	cc "github.com/arutselvan15/estore-common/clients"
+	"github.com/arutselvan15/estore-common/helper"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+// statusChange changes the product status. The changes are applied again to a fresh read of the product when
+// the write conflicts, so they must only depend on the status they are given.
+type statusChange func(status *pdtv1.ProductStatus)
+
+// writeStatus applies the changes to the product status and merge patches the status subresource with the
+// result. Nothing is written when the status stays the same apart from its update times.
+func writeStatus(ctx context.Context, pdt *pdtv1.Product, clients cc.EstoreClientInterface, changes ...statusChange) (*pdtv1.Product, error) {
+	return patchOnConflict(ctx, pdt, clients, func(current *pdtv1.Product) map[string]interface{} {
+		status := current.Status.DeepCopy()
+		for _, change := range changes {
+			change(status)
+		}
+
+		if statusEqual(&current.Status, status) {
+			return nil
+		}
+
+		status.CurrentStatus.LastUpdateTime = metav1.Now()
+
+		return map[string]interface{}{"status": status}
+	}, "status")
+}
+
+// addFinalizer adds the operator finalizer and returns the patched product
+func addFinalizer(ctx context.Context, pdt *pdtv1.Product, clients cc.EstoreClientInterface) (*pdtv1.Product, error) {
+	return patchOnConflict(ctx, pdt, clients, func(current *pdtv1.Product) map[string]interface{} {
+		if helper.ContainsString(current.Finalizers, cfg.ProductOperatorFinalizer) {
+			return nil
+		}
+
+		finalizers := append(append([]string{}, current.Finalizers...), cfg.ProductOperatorFinalizer)
+
+		return map[string]interface{}{"metadata": map[string]interface{}{"finalizers": finalizers}}
+	})
+}
+
+// removeFinalizer removes the operator finalizer and returns the patched product
+func removeFinalizer(ctx context.Context, pdt *pdtv1.Product, clients cc.EstoreClientInterface) (*pdtv1.Product, error) {
+	return patchOnConflict(ctx, pdt, clients, func(current *pdtv1.Product) map[string]interface{} {
+		if !helper.ContainsString(current.Finalizers, cfg.ProductOperatorFinalizer) {
+			return nil
+		}
+
+		return map[string]interface{}{"metadata": map[string]interface{}{
+			"finalizers": helper.RemoveString(current.Finalizers, cfg.ProductOperatorFinalizer),
+		}}
+	})
+}
+
+// patchOnConflict merge patches the product with the patch built from it, nil when there is nothing to write.
+// The patch carries the resource version it was built from, so it fails instead of overwriting a concurrent
+// change. On a conflict the product is read again and the patch rebuilt from the fresh copy. The clientset takes
+// no context, ctx is checked before every call instead.
+func patchOnConflict(ctx context.Context, pdt *pdtv1.Product, clients cc.EstoreClientInterface, patchFor func(current *pdtv1.Product) map[string]interface{},
+	subresources ...string) (*pdtv1.Product, error) {
+	pdtClient := clients.GetProductClient().EstoreV1().Products(pdt.Namespace)
+	current := pdt
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if current == nil {
+			fresh, err := pdtClient.Get(pdt.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+
+			current = fresh
+		}
+
+		patch := patchFor(current)
+		if patch == nil {
+			return nil
+		}
+
+		metadata, _ := patch["metadata"].(map[string]interface{})
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+			patch["metadata"] = metadata
+		}
+
+		metadata["resourceVersion"] = current.ResourceVersion
+
+		data, err := json.Marshal(patch)
+		if err != nil {
+			return err
+		}
+
+		patched, err := pdtClient.Patch(pdt.Name, types.MergePatchType, data, subresources...)
+		if err != nil {
+			current = nil
+			return err
+		}
+
+		current = patched
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return current, nil
+}
+
+// statusEqual true when the statuses only differ in their update times
+func statusEqual(a, b *pdtv1.ProductStatus) bool {
+	a, b = a.DeepCopy(), b.DeepCopy()
+	a.CurrentStatus.LastUpdateTime, b.CurrentStatus.LastUpdateTime = metav1.Time{}, metav1.Time{}
+	a.LastOperation.LastUpdateTime, b.LastOperation.LastUpdateTime = metav1.Time{}, metav1.Time{}
+
+	return apiequality.Semantic.DeepEqual(a, b)
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+
+	cc "github.com/arutselvan15/estore-common/clients"
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	pdtFake "github.com/arutselvan15/estore-product-kube-client/pkg/client/clientset/versioned/fake"
+)
+
+// injectConflicts fails the first n product patches with a conflict, as the api server does for a stale resource version
+func injectConflicts(clients cc.EstoreClientInterface, n int) {
+	clients.GetProductClient().(*pdtFake.Clientset).PrependReactor("patch", "products",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if n == 0 {
+				return false, nil, nil
+			}
+
+			n--
+
+			return true, nil, apierrors.NewConflict(pdtv1.Resource("products"), action.(k8stesting.PatchAction).GetName(),
+				errors.New("the object has been modified"))
+		})
+}
+
+// countActions number of product client actions with the verb
+func countActions(clients cc.EstoreClientInterface, verb string) int {
+	count := 0
+
+	for _, action := range clients.GetProductClient().(*pdtFake.Clientset).Actions() {
+		if action.GetVerb() == verb {
+			count++
+		}
+	}
+
+	return count
+}
+
+func Test_writeStatus(t *testing.T) {
+	ready := func(status *pdtv1.ProductStatus) {
+		setCondition(status, ConditionTypeReady, pdtv1.ConditionTrue, ReasonAvailable, "product available")
+	}
+
+	readyPdt := makeTestProduct()
+	ready(&readyPdt.Status)
+
+	// a condition written by another reconcile since the cached copy was read, only a fresh read has it
+	concurrentPdt := makeTestProduct()
+	setCondition(&concurrentPdt.Status, ConditionTypeSynced, pdtv1.ConditionTrue, ReasonSynced, "product synced to backend")
+
+	tests := []struct {
+		name        string
+		stored      *pdtv1.Product
+		cached      *pdtv1.Product
+		conflicts   int
+		wantPatches int
+		wantGets    int
+		wantConds   []pdtv1.ProductConditionType
+		wantErr     bool
+	}{
+		{name: "success status written", stored: makeTestProduct(), cached: makeTestProduct(), wantPatches: 1, wantConds: []pdtv1.ProductConditionType{ConditionTypeReady}},
+		{name: "success unchanged status not written", stored: readyPdt, cached: readyPdt, wantPatches: 0, wantConds: []pdtv1.ProductConditionType{ConditionTypeReady}},
+		{name: "success conflicts retried on a fresh read", stored: concurrentPdt, cached: makeTestProduct(), conflicts: 2, wantPatches: 3, wantGets: 2,
+			wantConds: []pdtv1.ProductConditionType{ConditionTypeSynced, ConditionTypeReady}},
+		{name: "failure conflicts exhausted", stored: makeTestProduct(), cached: makeTestProduct(), conflicts: 100, wantPatches: 5, wantGets: 4, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{tt.stored}, nil)
+			injectConflicts(fakeClients, tt.conflicts)
+
+			got, err := writeStatus(context.Background(), tt.cached, fakeClients, ready)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("writeStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil && !apierrors.IsConflict(err) {
+				t.Errorf("writeStatus() error = %v, want conflict", err)
+			}
+
+			if patches, gets := countActions(fakeClients, "patch"), countActions(fakeClients, "get"); patches != tt.wantPatches || gets != tt.wantGets {
+				t.Errorf("writeStatus() patches = %d, gets = %d, want %d, %d", patches, gets, tt.wantPatches, tt.wantGets)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			stored, err := fakeClients.GetProductClient().EstoreV1().Products(tt.cached.Namespace).Get(tt.cached.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			for _, condType := range tt.wantConds {
+				if getCondition(&stored.Status, condType) == nil || getCondition(&got.Status, condType) == nil {
+					t.Errorf("writeStatus() conditions = %+v, want %s", stored.Status.Conditions, condType)
+				}
+			}
+		})
+	}
+}
+
+func Test_statusEqual(t *testing.T) {
+	pdt := makeTestProduct()
+	setCondition(&pdt.Status, ConditionTypeReady, pdtv1.ConditionTrue, ReasonAvailable, "product available")
+
+	touched := pdt.Status.DeepCopy()
+	touched.CurrentStatus.LastUpdateTime = metav1.Now()
+	touched.LastOperation.LastUpdateTime = metav1.Now()
+
+	changed := pdt.Status.DeepCopy()
+	setCondition(changed, ConditionTypeReady, pdtv1.ConditionFalse, ReasonSyncFailed, "backend unavailable")
+
+	tests := []struct {
+		name string
+		b    *pdtv1.ProductStatus
+		want bool
+	}{
+		{name: "success update times ignored", b: touched, want: true},
+		{name: "success condition changed", b: changed, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusEqual(&pdt.Status, tt.b); got != tt.want {
+				t.Errorf("statusEqual() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
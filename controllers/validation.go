@@ -0,0 +1,62 @@
+// Package controllers controllers
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	cc "github.com/arutselvan15/estore-common/clients"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	lc "github.com/arutselvan15/go-utils/logconstants"
+
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
+	"github.com/arutselvan15/estore-product-kube-controller/validation"
+)
+
+// SpecValidator rules product specs are validated against before they are reconciled, the built-in rules of the
+// controller.validation settings when nil. Set it before the controller runs.
+var SpecValidator *validation.Validator
+
+func specValidator() *validation.Validator {
+	if SpecValidator != nil {
+		return SpecValidator
+	}
+
+	return validation.NewValidator(validation.DefaultRules()...)
+}
+
+// invalidate rejects a product with an invalid spec instead of reconciling it. It is not retried, only a spec
+// change fixes it. The status is only written when the violations change so resyncs do not repeat the warning.
+func invalidate(ctx context.Context, pdtCopy *pdtv1.Product, violations []validation.Violation, clients cc.EstoreClientInterface,
+	recorder record.EventRecorder) error {
+	message := validation.Message(violations)
+
+	if cond := getCondition(&pdtCopy.Status, ConditionTypeInvalid); cond != nil && cond.Status == pdtv1.ConditionTrue &&
+		cond.Message == message {
+		gLog.FromContext(ctx).SetObjectState(lc.Ignored).SetStepState(lc.Skip).Infof("process product %s skipped, still invalid: %s", pdtCopy.Name, message)
+		return nil
+	}
+
+	if _, err := writeStatus(ctx, pdtCopy, clients, func(status *pdtv1.ProductStatus) {
+		setCondition(status, ConditionTypeInvalid, pdtv1.ConditionTrue, ReasonValidationFailed, message)
+		setCondition(status, ConditionTypeReady, pdtv1.ConditionFalse, ReasonValidationFailed, message)
+		status.CurrentStatus.Phase = pdtv1.ProductFailed
+	}, lastOperation(ctx, pdtCopy, pdtv1.ProductStateFailed, ReasonValidationFailed, message)); err != nil {
+		handleError(ctx, pdtCopy, err, recorder)
+		return err
+	}
+
+	recorder.Event(pdtCopy, corev1.EventTypeWarning, "Invalid", message)
+	gLog.FromContext(ctx).SetObjectState(lc.Ignored).SetStepState(lc.Skip).Warnf("process product %s invalid: %s", pdtCopy.Name, message)
+
+	return nil
+}
+
+// validSpec marks a previously invalid product as valid
+func validSpec(status *pdtv1.ProductStatus) {
+	if getCondition(status, ConditionTypeInvalid) != nil {
+		setCondition(status, ConditionTypeInvalid, pdtv1.ConditionFalse, ReasonValid, "product spec is valid")
+	}
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	fakecc "github.com/arutselvan15/estore-common/clients/fake"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	"github.com/arutselvan15/estore-product-kube-controller/backend"
+)
+
+func TestProcessItem_invalid(t *testing.T) {
+	pdt := makeProduct("testNs", "testPdt", "", -5, []string{"test", "test"}, pdtv1.ProductPending)
+	fakeClients := fakecc.NewEstoreFakeClientForConfig([]runtime.Object{pdt}, nil)
+	pdtClient := fakeClients.GetProductClient().EstoreV1().Products(pdt.Namespace)
+	pdtBackend := backend.NewMemoryBackend()
+	recorder := record.NewFakeRecorder(fakeRecorderSize)
+
+	// not requeued: no error returned
+	if _, err := ProcessItem(context.Background(), pdt, fakeClients, pdtBackend, recorder); err != nil {
+		t.Fatalf("ProcessItem() invalid error = %v", err)
+	}
+
+	got, err := pdtClient.Get(pdt.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	wantMessage := "spec.price: -5 must be between 0 and 1e+06; spec.brand: must not be empty; spec.categories[1]: duplicate category \"test\""
+
+	if cond := getCondition(&got.Status, ConditionTypeInvalid); cond == nil || cond.Status != pdtv1.ConditionTrue ||
+		cond.Reason != ReasonValidationFailed || cond.Message != wantMessage {
+		t.Errorf("ProcessItem() invalid condition = %+v, want true %s", cond, wantMessage)
+	}
+
+	if got.Status.CurrentStatus.Phase != pdtv1.ProductFailed || len(got.Finalizers) != 0 || pdtBackend.Len() != 0 {
+		t.Errorf("ProcessItem() invalid phase = %v, finalizers = %v, backend = %d, want failed, none, 0",
+			got.Status.CurrentStatus.Phase, got.Finalizers, pdtBackend.Len())
+	}
+
+	if event := <-recorder.Events; event != "Warning Invalid "+wantMessage {
+		t.Errorf("ProcessItem() invalid event = %v", event)
+	}
+
+	// still invalid: no status write and no repeated warning
+	if _, err := ProcessItem(context.Background(), got, fakeClients, pdtBackend, recorder); err != nil || len(recorder.Events) != 0 {
+		t.Errorf("ProcessItem() still invalid error = %v, events = %d, want nil, 0", err, len(recorder.Events))
+	}
+
+	// spec fixed: product reconciled and valid
+	got.Spec.Brand, got.Spec.Price, got.Spec.Categories = "testBrand", 100, []string{"test"}
+
+	if _, err := ProcessItem(context.Background(), got, fakeClients, pdtBackend, recorder); err != nil {
+		t.Fatalf("ProcessItem() fixed error = %v", err)
+	}
+
+	if got, err = pdtClient.Get(pdt.Name, metav1.GetOptions{}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if cond := getCondition(&got.Status, ConditionTypeInvalid); cond == nil || cond.Status != pdtv1.ConditionFalse {
+		t.Errorf("ProcessItem() fixed condition = %+v, want false", cond)
+	}
+
+	if got.Status.CurrentStatus.Phase != pdtv1.ProductAvailable || pdtBackend.Len() != 1 {
+		t.Errorf("ProcessItem() fixed phase = %v, backend = %d, want available, 1", got.Status.CurrentStatus.Phase, pdtBackend.Len())
+	}
+}
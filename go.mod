@@ -4,6 +4,12 @@ require (
 	github.com/arutselvan15/estore-common v1.0.9
 	github.com/arutselvan15/estore-product-kube-client v1.0.5
 	github.com/arutselvan15/go-utils v1.0.7
+	github.com/fsnotify/fsnotify v1.4.7
+	github.com/prometheus/client_golang v1.4.1
+	github.com/sirupsen/logrus v1.4.2
+	github.com/spf13/pflag v1.0.3
+	github.com/spf13/viper v1.6.2
+	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
 	k8s.io/api v0.17.2
 	k8s.io/apimachinery v0.17.2
 	k8s.io/client-go v11.0.1-0.20190606204521-b8faab9c5193+incompatible
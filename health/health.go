@@ -0,0 +1,39 @@
+// Package health provides liveness and readiness probes
+package health
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	// LivenessPath liveness probe http path
+	LivenessPath = "/healthz"
+	// ReadinessPath readiness probe http path
+	ReadinessPath = "/readyz"
+)
+
+// Check returns an error when the probe should fail
+type Check func() error
+
+// NewServer http server serving the liveness and readiness checks
+func NewServer(addr string, liveness, readiness Check) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(LivenessPath, Handler(liveness))
+	mux.Handle(ReadinessPath, Handler(readiness))
+
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// Handler responds with 200 when the check passes, 503 with the error otherwise
+func Handler(check Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = fmt.Fprint(w, "ok")
+	})
+}
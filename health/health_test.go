@@ -0,0 +1,37 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewServer(t *testing.T) {
+	pass := func() error { return nil }
+	fail := func() error { return errors.New("not ready") }
+
+	tests := []struct {
+		name      string
+		liveness  Check
+		readiness Check
+		path      string
+		want      int
+	}{
+		{name: "success liveness", liveness: pass, readiness: fail, path: LivenessPath, want: http.StatusOK},
+		{name: "failure liveness", liveness: fail, readiness: pass, path: LivenessPath, want: http.StatusServiceUnavailable},
+		{name: "success readiness", liveness: fail, readiness: pass, path: ReadinessPath, want: http.StatusOK},
+		{name: "failure readiness", liveness: pass, readiness: fail, path: ReadinessPath, want: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewServer("", tt.liveness, tt.readiness).Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.want {
+				t.Errorf("GET %s = %v, want %v", tt.path, rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@
 package log
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"sync"
 
 	cl "github.com/arutselvan15/estore-common/log"
@@ -10,12 +13,21 @@ import (
 	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
 )
 
+// CorrelationIDField log field tying an event to the reconciles it triggers
+const CorrelationIDField = "correlationID"
+
+// Logger logger setting the audit fields on itself, it must not be shared between goroutines
+type Logger = gLog.CommonLog
+
 var (
 	logInstance gLog.CommonLog
 	once        sync.Once
 )
 
-// GetLogger the Log object
+type contextKey struct{}
+
+// GetLogger the Log object shared by the process, its fields must not be set as every goroutine logs with it.
+// Use NewLogger for a logger of its own.
 func GetLogger() gLog.CommonLog {
 	once.Do(func() {
 		logInstance = cl.GetLogger(cfg.ResourceName).SetComponent(cfg.Component)
@@ -23,3 +35,38 @@ func GetLogger() gLog.CommonLog {
 
 	return logInstance
 }
+
+// NewLogger logger of its own with the fields of the shared logger, its fields are not seen by other loggers
+func NewLogger() Logger {
+	return GetLogger().ThreadLogger()
+}
+
+// WithCorrelationID logger of its own logging the correlation id on every line
+func WithCorrelationID(id string) Logger {
+	l := GetLogger().ThreadLogger()
+	l.Entry = l.WithField(CorrelationIDField, id)
+
+	return l
+}
+
+// NewCorrelationID random correlation id
+func NewCorrelationID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// NewContext ctx carrying the logger
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext logger carried by ctx, a new logger when there is none
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return l
+	}
+
+	return NewLogger()
+}
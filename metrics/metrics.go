@@ -0,0 +1,135 @@
+// Package metrics provides prometheus metrics of the controller
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/labels"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	pdtv1Listers "github.com/arutselvan15/estore-product-kube-client/pkg/client/listers/estore/v1"
+)
+
+const (
+	namespace = "estore"
+	subsystem = "product_controller"
+
+	// Path metrics http path
+	Path = "/metrics"
+
+	// ResultSuccess reconcile completed successfully
+	ResultSuccess = "success"
+	// ResultError reconcile failed
+	ResultError = "error"
+	// ResultRequeue reconcile completed but the key was added back to the queue
+	ResultRequeue = "requeue"
+	// ResultDropped reconcile failed and the key exhausted its retries
+	ResultDropped = "dropped"
+	// ResultTimeout reconcile exceeded its timeout, the key is retried
+	ResultTimeout = "timeout"
+	// ResultPanic reconcile panicked, the key is retried
+	ResultPanic = "panic"
+	// ResultQuarantined reconcile panicked too often in a row, the key is quarantined and not retried
+	ResultQuarantined = "quarantined"
+)
+
+var (
+	reconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "reconcile_total",
+		Help:      "Total number of reconciles by result.",
+	}, []string{"result"})
+
+	processItemDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "process_item_duration_seconds",
+		Help:      "Latency of processing a product by result.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"result"})
+
+	productPhaseDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "products"),
+		"Number of products in the cache by phase.",
+		[]string{"phase"}, nil,
+	)
+
+	productPhases = []pdtv1.ProductPhase{
+		pdtv1.ProductPending, pdtv1.ProductAvailable, pdtv1.ProductOutOfStock,
+		pdtv1.ProductDeleting, pdtv1.ProductUnknown, pdtv1.ProductFailed,
+	}
+)
+
+func init() {
+	prometheus.MustRegister(reconcileTotal, processItemDuration)
+}
+
+// ObserveReconcile records the result of a reconcile
+func ObserveReconcile(result string) {
+	reconcileTotal.WithLabelValues(result).Inc()
+}
+
+// ObserveProcessItem records the latency of processing a product
+func ObserveProcessItem(result string, duration time.Duration) {
+	processItemDuration.WithLabelValues(result).Observe(duration.Seconds())
+}
+
+// RegisterProductPhases exposes the number of products in each phase listed from the lister
+func RegisterProductPhases(lister pdtv1Listers.ProductLister) error {
+	return prometheus.Register(&productPhaseCollector{lister: lister})
+}
+
+// Endpoint handler served next to the metrics, such as a debug endpoint
+type Endpoint struct {
+	Path    string
+	Handler http.Handler
+}
+
+// NewServer http server exposing the metrics on Path and the endpoints on their paths
+func NewServer(addr string, endpoints ...Endpoint) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(Path, promhttp.Handler())
+
+	for _, endpoint := range endpoints {
+		mux.Handle(endpoint.Path, endpoint.Handler)
+	}
+
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+type productPhaseCollector struct {
+	lister pdtv1Listers.ProductLister
+}
+
+func (c *productPhaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- productPhaseDesc
+}
+
+func (c *productPhaseCollector) Collect(ch chan<- prometheus.Metric) {
+	pdts, err := c.lister.List(labels.Everything())
+	if err != nil {
+		return
+	}
+
+	counts := make(map[pdtv1.ProductPhase]int, len(productPhases))
+	for _, phase := range productPhases {
+		counts[phase] = 0
+	}
+
+	for _, pdt := range pdts {
+		phase := pdt.Status.CurrentStatus.Phase
+		if phase == "" {
+			phase = pdtv1.ProductUnknown
+		}
+
+		counts[phase]++
+	}
+
+	for phase, count := range counts {
+		ch <- prometheus.MustNewConstMetric(productPhaseDesc, prometheus.GaugeValue, float64(count), string(phase))
+	}
+}
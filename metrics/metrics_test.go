@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+	pdtv1Listers "github.com/arutselvan15/estore-product-kube-client/pkg/client/listers/estore/v1"
+)
+
+func makeProduct(name string, phase pdtv1.ProductPhase) *pdtv1.Product {
+	return &pdtv1.Product{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "testNs"},
+		Status:     pdtv1.ProductStatus{CurrentStatus: pdtv1.CurrentStatus{Phase: phase}},
+	}
+}
+
+func TestObserveReconcile(t *testing.T) {
+	tests := []struct {
+		name   string
+		result string
+	}{
+		{name: "success reconcile success", result: ResultSuccess},
+		{name: "success reconcile error", result: ResultError},
+		{name: "success reconcile requeue", result: ResultRequeue},
+		{name: "success reconcile dropped", result: ResultDropped},
+		{name: "success reconcile timeout", result: ResultTimeout},
+		{name: "success reconcile panic", result: ResultPanic},
+		{name: "success reconcile quarantined", result: ResultQuarantined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(reconcileTotal.WithLabelValues(tt.result))
+			ObserveReconcile(tt.result)
+			ObserveProcessItem(tt.result, time.Millisecond)
+
+			if got := testutil.ToFloat64(reconcileTotal.WithLabelValues(tt.result)); got != before+1 {
+				t.Errorf("ObserveReconcile() = %v, want %v", got, before+1)
+			}
+		})
+	}
+}
+
+func TestProductPhaseCollector(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	_ = indexer.Add(makeProduct("available1", pdtv1.ProductAvailable))
+	_ = indexer.Add(makeProduct("available2", pdtv1.ProductAvailable))
+	_ = indexer.Add(makeProduct("failed", pdtv1.ProductFailed))
+	_ = indexer.Add(makeProduct("new", ""))
+
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(&productPhaseCollector{lister: pdtv1Listers.NewProductLister(indexer)}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	expected := `
+# HELP estore_product_controller_products Number of products in the cache by phase.
+# TYPE estore_product_controller_products gauge
+estore_product_controller_products{phase="Available"} 2
+estore_product_controller_products{phase="Deleting"} 0
+estore_product_controller_products{phase="Failed"} 1
+estore_product_controller_products{phase="OutOfStock"} 0
+estore_product_controller_products{phase="Pending"} 0
+estore_product_controller_products{phase="Unknown"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Errorf("productPhaseCollector %v", err)
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test_metrics")
+	queue.Add("testNs/testPdt")
+
+	srv := httptest.NewServer(NewServer("").Handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + Path)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", Path, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	for _, want := range []string{`workqueue_depth{name="test_metrics"} 1`, `workqueue_adds_total{name="test_metrics"} 1`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("GET %s missing %s", Path, want)
+		}
+	}
+}
+
+func TestNewServer_endpoints(t *testing.T) {
+	srv := httptest.NewServer(NewServer("", Endpoint{Path: "/debug/test", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("debug"))
+	})}).Handler)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/debug/test")
+	if err != nil {
+		t.Fatalf("GET /debug/test error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if body, _ := ioutil.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(body) != "debug" {
+		t.Errorf("GET /debug/test = %v %q, want %v %q", resp.StatusCode, body, http.StatusOK, "debug")
+	}
+}
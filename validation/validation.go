@@ -0,0 +1,150 @@
+// Package validation provides the rules product specs are validated against
+package validation
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/arutselvan15/estore-common/helper"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+// Violation a product field breaking a rule
+type Violation struct {
+	Field   string
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Field + ": " + v.Message
+}
+
+// Rule returns the violations of the product, none when it is valid
+type Rule func(pdt *pdtv1.Product) []Violation
+
+// Validator validates products against a rule set
+type Validator struct {
+	rules []Rule
+}
+
+// NewValidator validator of the rules, further rules can be plugged in with With
+func NewValidator(rules ...Rule) *Validator {
+	return &Validator{rules: rules}
+}
+
+// With validator of the rules of v followed by the given rules
+func (v *Validator) With(rules ...Rule) *Validator {
+	return &Validator{rules: append(append([]Rule{}, v.rules...), rules...)}
+}
+
+// Validate violations of every rule, in rule order
+func (v *Validator) Validate(pdt *pdtv1.Product) []Violation {
+	var violations []Violation
+
+	for _, rule := range v.rules {
+		violations = append(violations, rule(pdt)...)
+	}
+
+	return violations
+}
+
+// Message violations joined into a single message
+func Message(violations []Violation) string {
+	msgs := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		msgs = append(msgs, violation.String())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// DefaultRules built-in rules configured by the controller.validation settings
+func DefaultRules() []Rule {
+	return []Rule{
+		PriceRange(cfg.ValidationPriceMin, cfg.ValidationPriceMax),
+		CurrencyPrecision(cfg.ValidationCurrencyDecimals),
+		BrandRequired(),
+		CategoryAllowlist(cfg.ValidationCategories),
+		DisplayNameFormat(regexp.MustCompile(cfg.ValidationDisplayNamePattern)),
+	}
+}
+
+// PriceRange price within min and max
+func PriceRange(min, max float64) Rule {
+	return func(pdt *pdtv1.Product) []Violation {
+		if price := pdt.Spec.Price; price < min || price > max || math.IsNaN(price) {
+			return []Violation{{Field: "spec.price", Message: fmt.Sprintf("%v must be between %v and %v", price, min, max)}}
+		}
+
+		return nil
+	}
+}
+
+// CurrencyPrecision price with at most decimals decimal places
+func CurrencyPrecision(decimals int) Rule {
+	scale := math.Pow10(decimals)
+
+	return func(pdt *pdtv1.Product) []Violation {
+		scaled := pdt.Spec.Price * scale
+		if math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+			return []Violation{{Field: "spec.price", Message: fmt.Sprintf("%v must not have more than %d decimal places", pdt.Spec.Price, decimals)}}
+		}
+
+		return nil
+	}
+}
+
+// BrandRequired brand not empty
+func BrandRequired() Rule {
+	return func(pdt *pdtv1.Product) []Violation {
+		if strings.TrimSpace(pdt.Spec.Brand) == "" {
+			return []Violation{{Field: "spec.brand", Message: "must not be empty"}}
+		}
+
+		return nil
+	}
+}
+
+// CategoryAllowlist at least one category, no duplicates and, unless allowed is empty, only allowed categories
+func CategoryAllowlist(allowed []string) Rule {
+	return func(pdt *pdtv1.Product) []Violation {
+		if len(pdt.Spec.Categories) == 0 {
+			return []Violation{{Field: "spec.categories", Message: "must not be empty"}}
+		}
+
+		var violations []Violation
+
+		seen := map[string]bool{}
+
+		for i, category := range pdt.Spec.Categories {
+			field := fmt.Sprintf("spec.categories[%d]", i)
+
+			switch {
+			case seen[category]:
+				violations = append(violations, Violation{Field: field, Message: fmt.Sprintf("duplicate category %q", category)})
+			case len(allowed) > 0 && !helper.ContainsString(allowed, category):
+				violations = append(violations, Violation{Field: field, Message: fmt.Sprintf("category %q must be one of %s",
+					category, strings.Join(allowed, ", "))})
+			}
+
+			seen[category] = true
+		}
+
+		return violations
+	}
+}
+
+// DisplayNameFormat display name, when set, matching pattern
+func DisplayNameFormat(pattern *regexp.Regexp) Rule {
+	return func(pdt *pdtv1.Product) []Violation {
+		if name := pdt.Spec.DisplayName; name != "" && !pattern.MatchString(name) {
+			return []Violation{{Field: "spec.displayName", Message: fmt.Sprintf("%q must match %s", name, pattern)}}
+		}
+
+		return nil
+	}
+}
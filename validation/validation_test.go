@@ -0,0 +1,107 @@
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+)
+
+func makeProduct(displayName, brand string, price float64, categories ...string) *pdtv1.Product {
+	return &pdtv1.Product{
+		ObjectMeta: metav1.ObjectMeta{Name: "testpdt", Namespace: "testns"},
+		Spec:       pdtv1.ProductSpec{DisplayName: displayName, Brand: brand, Price: price, Categories: categories},
+	}
+}
+
+func fields(violations []Violation) []string {
+	var got []string
+	for _, violation := range violations {
+		got = append(got, violation.Field)
+	}
+
+	return got
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		pdt  *pdtv1.Product
+		want []string
+	}{
+		{name: "success price in range", rule: PriceRange(0, 100), pdt: makeProduct("", "b", 100, "c")},
+		{name: "failure negative price", rule: PriceRange(0, 100), pdt: makeProduct("", "b", -1, "c"), want: []string{"spec.price"}},
+		{name: "failure price above max", rule: PriceRange(0, 100), pdt: makeProduct("", "b", 100.01, "c"), want: []string{"spec.price"}},
+		{name: "success cents", rule: CurrencyPrecision(2), pdt: makeProduct("", "b", 19.99, "c")},
+		{name: "failure fraction of a cent", rule: CurrencyPrecision(2), pdt: makeProduct("", "b", 19.999, "c"), want: []string{"spec.price"}},
+		{name: "failure cents without decimals", rule: CurrencyPrecision(0), pdt: makeProduct("", "b", 19.5, "c"), want: []string{"spec.price"}},
+		{name: "success brand", rule: BrandRequired(), pdt: makeProduct("", "b", 1, "c")},
+		{name: "failure blank brand", rule: BrandRequired(), pdt: makeProduct("", " ", 1, "c"), want: []string{"spec.brand"}},
+		{name: "success any category", rule: CategoryAllowlist(nil), pdt: makeProduct("", "b", 1, "c", "d")},
+		{name: "success allowed category", rule: CategoryAllowlist([]string{"c", "d"}), pdt: makeProduct("", "b", 1, "d")},
+		{name: "failure no categories", rule: CategoryAllowlist(nil), pdt: makeProduct("", "b", 1), want: []string{"spec.categories"}},
+		{name: "failure duplicate and unknown categories", rule: CategoryAllowlist([]string{"c"}), pdt: makeProduct("", "b", 1, "c", "c", "x"),
+			want: []string{"spec.categories[1]", "spec.categories[2]"}},
+		{name: "success display name unset", rule: DisplayNameFormat(regexp.MustCompile(`^[a-z]+$`)), pdt: makeProduct("", "b", 1, "c")},
+		{name: "success display name", rule: DisplayNameFormat(regexp.MustCompile(`^[a-z]+$`)), pdt: makeProduct("phone", "b", 1, "c")},
+		{name: "failure display name format", rule: DisplayNameFormat(regexp.MustCompile(`^[a-z]+$`)), pdt: makeProduct("Phone!", "b", 1, "c"),
+			want: []string{"spec.displayName"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fields(tt.rule(tt.pdt)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rule() violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidator_Validate(t *testing.T) {
+	extraRule := func(pdt *pdtv1.Product) []Violation {
+		if pdt.Spec.Description == "" {
+			return []Violation{{Field: "spec.description", Message: "must not be empty"}}
+		}
+
+		return nil
+	}
+
+	tests := []struct {
+		name      string
+		validator *Validator
+		pdt       *pdtv1.Product
+		want      string
+	}{
+		{name: "success default rules", validator: NewValidator(DefaultRules()...), pdt: makeProduct("Smart Phone 5G", "b", 199.99, "c")},
+		{name: "failure every violation listed", validator: NewValidator(DefaultRules()...), pdt: makeProduct("", "", -1.001, "c", "c"),
+			want: "spec.price: -1.001 must be between 0 and 1e+06; spec.price: -1.001 must not have more than 2 decimal places; " +
+				"spec.brand: must not be empty; spec.categories[1]: duplicate category \"c\""},
+		{name: "failure plugged in rule", validator: NewValidator(BrandRequired()).With(extraRule), pdt: makeProduct("", "", 1, "c"),
+			want: "spec.brand: must not be empty; spec.description: must not be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Message(tt.validator.Validate(tt.pdt)); got != tt.want {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	categories := cfg.ValidationCategories
+	defer func() { cfg.ValidationCategories = categories }()
+
+	cfg.ValidationCategories = []string{"phones"}
+
+	if got := fields(NewValidator(DefaultRules()...).Validate(makeProduct("", "b", 1, "laptops"))); !reflect.DeepEqual(got, []string{"spec.categories[0]"}) {
+		t.Errorf("DefaultRules() violations = %v, want the configured allowlist applied", got)
+	}
+}
@@ -0,0 +1,265 @@
+// Package webhook provides the validating and mutating admission webhooks of products
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/arutselvan15/estore-common/helper"
+	"github.com/arutselvan15/estore-common/validate"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+	gLog "github.com/arutselvan15/estore-product-kube-controller/log"
+	"github.com/arutselvan15/estore-product-kube-controller/validation"
+)
+
+const (
+	// ValidatePath validating webhook http path
+	ValidatePath = "/validate"
+	// MutatePath mutating webhook http path
+	MutatePath = "/mutate"
+	// Component name of the webhooks in the app.freeze components
+	Component = "webhook"
+
+	// maxRequestSize largest admission review accepted
+	maxRequestSize = 3 << 20
+)
+
+var log = gLog.GetLogger()
+
+// admitFunc decides on an admission request
+type admitFunc func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// Scope products reconciled by the controller
+type Scope interface {
+	Contains(pdt *pdtv1.Product) bool
+}
+
+// NewServer server of the admission webhooks, products are validated against the rules of validator and only the
+// ones in scope get the operator finalizer
+func NewServer(addr string, validator *validation.Validator, scope Scope) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle(ValidatePath, Handler(func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+		return Validate(req, validator, time.Now())
+	}))
+	mux.Handle(MutatePath, Handler(func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+		return Mutate(req, scope)
+	}))
+
+	return &http.Server{Addr: addr, Handler: mux}
+}
+
+// Handler decodes the admission review, admits its request and responds with the review of the same version
+func Handler(admit admitFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "admission reviews must be posted", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+			http.Error(w, fmt.Sprintf("content type %q must be application/json", contentType), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		review := admissionv1.AdmissionReview{}
+		if err = json.Unmarshal(body, &review); err != nil || review.Request == nil {
+			http.Error(w, fmt.Sprintf("invalid admission review: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// v1beta1 and v1 reviews share their fields, the response is sent in the version of the request
+		if review.APIVersion == "" {
+			review.APIVersion, review.Kind = admissionv1.SchemeGroupVersion.String(), "AdmissionReview"
+		}
+
+		review.Response = admit(review.Request)
+		review.Response.UID = review.Request.UID
+		review.Request = nil
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err = json.NewEncoder(w).Encode(review); err != nil {
+			log.Errorf("writing admission response failed: %v", err)
+		}
+	})
+}
+
+// Validate rejects products in blacklisted namespaces or from blacklisted users, changes inside the freeze window
+// and specs breaking the validation rules. Requests of system users are only validated against the rules, updates
+// leaving the spec unchanged, such as the finalizer and annotation patches of the controller, are not validated.
+func Validate(req *admissionv1.AdmissionRequest, validator *validation.Validator, now time.Time) *admissionv1.AdmissionResponse {
+	// status updates and the finalizer release of deleted products are left to the controller
+	if req.SubResource != "" {
+		return allowed()
+	}
+
+	rt := cfg.CurrentRuntime()
+	systemUser := cfg.Matches(req.UserInfo.Username, rt.SystemUsers)
+	frozen := rt.Freeze.Enabled(Component, now) && !systemUser && !cfg.Matches(req.Namespace, rt.SystemNamespaces)
+
+	if req.Operation == admissionv1.Delete {
+		if frozen && !cfg.FreezeAllowDeletes {
+			return denied(http.StatusForbidden, metav1.StatusReasonForbidden, rt.Freeze.Message, req)
+		}
+
+		return allowed()
+	}
+
+	pdt, err := decode(req)
+	if err != nil {
+		return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error(), req)
+	}
+
+	if pdt.DeletionTimestamp != nil || (req.Operation == admissionv1.Update && !specChanged(req, pdt)) {
+		return allowed()
+	}
+
+	if !systemUser {
+		if cfg.Matches(req.Namespace, rt.BlacklistNamespaces) {
+			return denied(http.StatusForbidden, metav1.StatusReasonForbidden, "namespace "+req.Namespace+" is blacklisted", req)
+		}
+
+		for _, user := range []string{req.UserInfo.Username, pdt.Annotations[pdtv1.ProductAnnotationRequester]} {
+			if user != "" && cfg.Matches(user, rt.BlacklistUsers) {
+				return denied(http.StatusForbidden, metav1.StatusReasonForbidden, "user "+user+" is blacklisted", req)
+			}
+		}
+	}
+
+	if frozen {
+		return denied(http.StatusForbidden, metav1.StatusReasonForbidden, rt.Freeze.Message, req)
+	}
+
+	if violations := validator.Validate(pdt); len(violations) > 0 {
+		return denied(http.StatusUnprocessableEntity, metav1.StatusReasonInvalid, validation.Message(violations), req)
+	}
+
+	return allowed()
+}
+
+// Mutate normalizes the categories of created and updated products and adds the operator finalizer to created ones
+// in scope, no controller would remove it from the others
+func Mutate(req *admissionv1.AdmissionRequest, scope Scope) *admissionv1.AdmissionResponse {
+	if req.SubResource != "" || (req.Operation != admissionv1.Create && req.Operation != admissionv1.Update) {
+		return allowed()
+	}
+
+	pdt, err := decode(req)
+	if err != nil {
+		return denied(http.StatusBadRequest, metav1.StatusReasonBadRequest, err.Error(), req)
+	}
+
+	if pdt.DeletionTimestamp != nil {
+		return allowed()
+	}
+
+	// the namespace of a created product may only be set on the request
+	if pdt.Namespace == "" {
+		pdt.Namespace = req.Namespace
+	}
+
+	var patch []validate.PatchOperation
+
+	if categories := normalizeCategories(pdt.Spec.Categories); len(pdt.Spec.Categories) > 0 && !equal(categories, pdt.Spec.Categories) {
+		patch = append(patch, validate.PatchOperation{Op: "replace", Path: "/spec/categories", Value: categories})
+	}
+
+	if req.Operation == admissionv1.Create && scope.Contains(pdt) && !helper.ContainsString(pdt.Finalizers, cfg.ProductOperatorFinalizer) {
+		if len(pdt.Finalizers) == 0 {
+			patch = append(patch, validate.PatchOperation{Op: "add", Path: "/metadata/finalizers", Value: []string{cfg.ProductOperatorFinalizer}})
+		} else {
+			patch = append(patch, validate.PatchOperation{Op: "add", Path: "/metadata/finalizers/-", Value: cfg.ProductOperatorFinalizer})
+		}
+	}
+
+	if len(patch) == 0 {
+		return allowed()
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return denied(http.StatusInternalServerError, metav1.StatusReasonInternalError, err.Error(), req)
+	}
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	response := allowed()
+	response.Patch, response.PatchType = data, &patchType
+
+	return response
+}
+
+// normalizeCategories trimmed lower case categories without empty ones and duplicates, in their original order
+func normalizeCategories(categories []string) []string {
+	normalized := []string{}
+
+	for _, category := range categories {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category != "" && !helper.ContainsString(normalized, category) {
+			normalized = append(normalized, category)
+		}
+	}
+
+	return normalized
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// specChanged true when the update changes the spec of the product, or the product before the update is unknown
+func specChanged(req *admissionv1.AdmissionRequest, pdt *pdtv1.Product) bool {
+	oldPdt := &pdtv1.Product{}
+	if len(req.OldObject.Raw) == 0 || json.Unmarshal(req.OldObject.Raw, oldPdt) != nil {
+		return true
+	}
+
+	return !apiequality.Semantic.DeepEqual(oldPdt.Spec, pdt.Spec)
+}
+
+func decode(req *admissionv1.AdmissionRequest) (*pdtv1.Product, error) {
+	pdt := &pdtv1.Product{}
+	if err := json.Unmarshal(req.Object.Raw, pdt); err != nil {
+		return nil, fmt.Errorf("decoding product %s/%s failed: %v", req.Namespace, req.Name, err)
+	}
+
+	return pdt, nil
+}
+
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+func denied(code int32, reason metav1.StatusReason, message string, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	log.Warnf("%s of product %s/%s by %s denied: %s", req.Operation, req.Namespace, req.Name, req.UserInfo.Username, message)
+
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result:  &metav1.Status{Status: metav1.StatusFailure, Code: code, Reason: reason, Message: message},
+	}
+}
@@ -0,0 +1,305 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/arutselvan15/estore-common/validate"
+	pdtv1 "github.com/arutselvan15/estore-product-kube-client/pkg/apis/estore/v1"
+
+	cfg "github.com/arutselvan15/estore-product-kube-controller/config"
+	"github.com/arutselvan15/estore-product-kube-controller/validation"
+)
+
+// setRuntime sets the runtime settings for the test, restoring them afterwards
+func setRuntime(t *testing.T, rt *cfg.Runtime) {
+	old := cfg.CurrentRuntime()
+
+	t.Cleanup(func() { cfg.SetRuntime(old) })
+	cfg.SetRuntime(rt)
+}
+
+func makeProduct(namespace, brand string, price float64, categories []string) *pdtv1.Product {
+	return &pdtv1.Product{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "estore.com/v1", Kind: "Product"},
+		ObjectMeta: metav1.ObjectMeta{Name: "testPdt", Namespace: namespace},
+		Spec:       pdtv1.ProductSpec{Brand: brand, Price: price, Categories: categories},
+	}
+}
+
+// makeReview admission review of the operation on the product by user, pdt nil for deletes and oldPdt nil unless
+// the update carries the product before it
+func makeReview(t *testing.T, op admissionv1.Operation, user string, pdt, oldPdt *pdtv1.Product) []byte {
+	req := &admissionv1.AdmissionRequest{
+		UID:       types.UID("test-uid"),
+		Kind:      metav1.GroupVersionKind{Group: "estore.com", Version: "v1", Kind: "Product"},
+		Resource:  metav1.GroupVersionResource{Group: "estore.com", Version: "v1", Resource: "products"},
+		Name:      "testPdt",
+		Namespace: "testNs",
+		Operation: op,
+		UserInfo:  authenticationv1.UserInfo{Username: user},
+	}
+
+	if pdt != nil {
+		raw, err := json.Marshal(pdt)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+
+		req.Namespace, req.Object = pdt.Namespace, runtime.RawExtension{Raw: raw}
+	}
+
+	if oldPdt != nil {
+		raw, err := json.Marshal(oldPdt)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+
+	review, err := json.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request:  req,
+	})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	return review
+}
+
+// post posts the body to the path of the webhook server and returns the decoded response
+func post(t *testing.T, server *httptest.Server, path string, body []byte) (int, *admissionv1.AdmissionReview) {
+	resp, err := http.Post(server.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+
+	review := &admissionv1.AdmissionReview{}
+	if err = json.NewDecoder(resp.Body).Decode(review); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	return resp.StatusCode, review
+}
+
+// testScope products of the testNs namespace
+type testScope struct{}
+
+func (testScope) Contains(pdt *pdtv1.Product) bool {
+	return pdt.Namespace == "testNs"
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(NewServer("", validation.NewValidator(validation.BrandRequired(),
+		validation.CategoryAllowlist(nil)), testScope{}).Handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	freeze := cfg.FreezeWindow{Start: now.Add(-time.Hour), Message: "store frozen", Components: []string{Component}}
+
+	withFinalizer := makeProduct("testNs", "testBrand", 100, []string{"test"})
+	withFinalizer.Finalizers = []string{cfg.ProductOperatorFinalizer}
+	withFinalizer.Annotations = map[string]string{cfg.ObservedGenerationAnnotation: "1"}
+
+	tests := []struct {
+		name        string
+		rt          *cfg.Runtime
+		op          admissionv1.Operation
+		user        string
+		pdt         *pdtv1.Product
+		oldPdt      *pdtv1.Product
+		wantAllowed bool
+		wantCode    int32
+		wantMessage string
+	}{
+		{name: "success valid create", rt: &cfg.Runtime{}, op: admissionv1.Create, user: "dev", pdt: makeProduct("testNs", "testBrand", 100, []string{"test"}), wantAllowed: true},
+		{name: "failure invalid spec", rt: &cfg.Runtime{}, op: admissionv1.Create, user: "dev", pdt: makeProduct("testNs", "", 100, nil), wantCode: http.StatusUnprocessableEntity,
+			wantMessage: "spec.brand: must not be empty; spec.categories: must not be empty"},
+		{name: "failure blacklisted namespace", rt: &cfg.Runtime{BlacklistNamespaces: []string{"black"}}, op: admissionv1.Create, user: "dev", pdt: makeProduct("blackNs", "testBrand", 100, []string{"test"}),
+			wantCode: http.StatusForbidden, wantMessage: "namespace blackNs is blacklisted"},
+		{name: "failure blacklisted user", rt: &cfg.Runtime{BlacklistUsers: []string{"bad"}}, op: admissionv1.Update, user: "badUser", pdt: makeProduct("testNs", "testBrand", 100, []string{"test"}),
+			wantCode: http.StatusForbidden, wantMessage: "user badUser is blacklisted"},
+		{name: "success blacklisted namespace system user", rt: &cfg.Runtime{BlacklistNamespaces: []string{"black"}, SystemUsers: []string{"system:"}}, op: admissionv1.Create,
+			user: "system:admin", pdt: makeProduct("blackNs", "testBrand", 100, []string{"test"}), wantAllowed: true},
+		{name: "failure freeze", rt: &cfg.Runtime{Freeze: freeze}, op: admissionv1.Create, user: "dev", pdt: makeProduct("testNs", "testBrand", 100, []string{"test"}),
+			wantCode: http.StatusForbidden, wantMessage: "store frozen"},
+		{name: "success freeze system namespace", rt: &cfg.Runtime{Freeze: freeze, SystemNamespaces: []string{"kube-"}}, op: admissionv1.Create, user: "dev",
+			pdt: makeProduct("kube-system", "testBrand", 100, []string{"test"}), wantAllowed: true},
+		{name: "success freeze other component", rt: &cfg.Runtime{Freeze: cfg.FreezeWindow{Start: freeze.Start, Components: []string{"controller"}}}, op: admissionv1.Create,
+			user: "dev", pdt: makeProduct("testNs", "testBrand", 100, []string{"test"}), wantAllowed: true},
+		{name: "success delete during freeze", rt: &cfg.Runtime{Freeze: freeze}, op: admissionv1.Delete, user: "dev", wantAllowed: true},
+		{name: "success metadata only update during freeze", rt: &cfg.Runtime{Freeze: freeze, BlacklistUsers: []string{"system:serviceaccount"}}, op: admissionv1.Update,
+			user: "system:serviceaccount:estore:product-controller", pdt: withFinalizer, oldPdt: makeProduct("testNs", "testBrand", 100, []string{"test"}), wantAllowed: true},
+		{name: "failure spec update during freeze", rt: &cfg.Runtime{Freeze: freeze}, op: admissionv1.Update, user: "dev",
+			pdt: makeProduct("testNs", "testBrand", 200, []string{"test"}), oldPdt: makeProduct("testNs", "testBrand", 100, []string{"test"}),
+			wantCode: http.StatusForbidden, wantMessage: "store frozen"},
+	}
+
+	server := newTestServer(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRuntime(t, tt.rt)
+
+			code, review := post(t, server, ValidatePath, makeReview(t, tt.op, tt.user, tt.pdt, tt.oldPdt))
+			if code != http.StatusOK {
+				t.Fatalf("Validate() http status = %v, want %v", code, http.StatusOK)
+			}
+
+			if review.Response.UID != "test-uid" || review.APIVersion != "admission.k8s.io/v1" || review.Kind != "AdmissionReview" {
+				t.Errorf("Validate() review = %+v, want uid test-uid of an admission.k8s.io/v1 AdmissionReview", review)
+			}
+
+			if review.Response.Allowed != tt.wantAllowed {
+				t.Fatalf("Validate() allowed = %v, want %v, result %+v", review.Response.Allowed, tt.wantAllowed, review.Response.Result)
+			}
+
+			if !tt.wantAllowed && (review.Response.Result.Code != tt.wantCode || review.Response.Result.Message != tt.wantMessage) {
+				t.Errorf("Validate() result = %d %q, want %d %q", review.Response.Result.Code, review.Response.Result.Message, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestValidate_deleteDuringFreeze(t *testing.T) {
+	old := cfg.FreezeAllowDeletes
+
+	t.Cleanup(func() { cfg.FreezeAllowDeletes = old })
+
+	cfg.FreezeAllowDeletes = false
+
+	setRuntime(t, &cfg.Runtime{Freeze: cfg.FreezeWindow{Start: time.Now().Add(-time.Hour), Message: "store frozen", Components: []string{"all"}}})
+
+	resp := Validate(&admissionv1.AdmissionRequest{Operation: admissionv1.Delete, Namespace: "testNs", Name: "testPdt"}, validation.NewValidator(), time.Now())
+	if resp.Allowed || resp.Result.Code != http.StatusForbidden {
+		t.Errorf("Validate() = %+v, want delete denied during freeze", resp)
+	}
+}
+
+func TestMutate(t *testing.T) {
+	withFinalizer := makeProduct("testNs", "testBrand", 100, []string{"test"})
+	withFinalizer.Finalizers = []string{"other"}
+
+	tests := []struct {
+		name      string
+		op        admissionv1.Operation
+		pdt       *pdtv1.Product
+		wantPatch []validate.PatchOperation
+	}{
+		{name: "success create normalized with finalizer", op: admissionv1.Create, pdt: makeProduct("testNs", "testBrand", 100, []string{" Books ", "books", "", "Toys"}),
+			wantPatch: []validate.PatchOperation{
+				{Op: "replace", Path: "/spec/categories", Value: []interface{}{"books", "toys"}},
+				{Op: "add", Path: "/metadata/finalizers", Value: []interface{}{cfg.ProductOperatorFinalizer}},
+			}},
+		{name: "success create appends finalizer", op: admissionv1.Create, pdt: withFinalizer,
+			wantPatch: []validate.PatchOperation{{Op: "add", Path: "/metadata/finalizers/-", Value: cfg.ProductOperatorFinalizer}}},
+		{name: "success create out of scope normalized without finalizer", op: admissionv1.Create, pdt: makeProduct("otherNs", "testBrand", 100, []string{"Books"}),
+			wantPatch: []validate.PatchOperation{{Op: "replace", Path: "/spec/categories", Value: []interface{}{"books"}}}},
+		{name: "success create out of scope unchanged", op: admissionv1.Create, pdt: makeProduct("otherNs", "testBrand", 100, []string{"books"})},
+		{name: "success update normalized", op: admissionv1.Update, pdt: makeProduct("testNs", "testBrand", 100, []string{"Books"}),
+			wantPatch: []validate.PatchOperation{{Op: "replace", Path: "/spec/categories", Value: []interface{}{"books"}}}},
+		{name: "success update unchanged", op: admissionv1.Update, pdt: makeProduct("testNs", "testBrand", 100, []string{"books"})},
+		{name: "success delete unchanged", op: admissionv1.Delete},
+	}
+
+	server := newTestServer(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, review := post(t, server, MutatePath, makeReview(t, tt.op, "dev", tt.pdt, nil))
+			if code != http.StatusOK {
+				t.Fatalf("Mutate() http status = %v, want %v", code, http.StatusOK)
+			}
+
+			if !review.Response.Allowed {
+				t.Fatalf("Mutate() allowed = false, result %+v", review.Response.Result)
+			}
+
+			if len(tt.wantPatch) == 0 {
+				if review.Response.Patch != nil || review.Response.PatchType != nil {
+					t.Errorf("Mutate() patch = %s, want none", review.Response.Patch)
+				}
+
+				return
+			}
+
+			if review.Response.PatchType == nil || *review.Response.PatchType != admissionv1.PatchTypeJSONPatch {
+				t.Errorf("Mutate() patch type = %v, want %v", review.Response.PatchType, admissionv1.PatchTypeJSONPatch)
+			}
+
+			var got []validate.PatchOperation
+			if err := json.Unmarshal(review.Response.Patch, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.wantPatch)
+
+			if !bytes.Equal(gotJSON, wantJSON) {
+				t.Errorf("Mutate() patch = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestHandler_badRequest(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{name: "failure not posted", method: http.MethodGet, contentType: "application/json", wantCode: http.StatusMethodNotAllowed},
+		{name: "failure content type", method: http.MethodPost, contentType: "text/plain", body: "{}", wantCode: http.StatusUnsupportedMediaType},
+		{name: "failure malformed review", method: http.MethodPost, contentType: "application/json", body: "{", wantCode: http.StatusBadRequest},
+		{name: "failure review without request", method: http.MethodPost, contentType: "application/json", body: "{}", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+ValidatePath, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+
+			req.Header.Set("Content-Type", tt.contentType)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("Handler() http status = %v, want %v", resp.StatusCode, tt.wantCode)
+			}
+		})
+	}
+}